@@ -0,0 +1,62 @@
+package adapters
+
+import (
+	"backend/models"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// CalendarProvider is the set of operations CalendarService needs from a
+// calendar backend. GoogleAdapter is the production implementation.
+type CalendarProvider interface {
+	ListCalendars(accessToken string) ([]models.Calendar, error)
+	CreateCalendar(accessToken string, c models.CreateCalendar) (models.Calendar, error)
+	PatchCalendar(accessToken, calendarID string, c models.EditCalendar) (models.Calendar, error)
+	DeleteCalendar(accessToken, calendarID string) error
+	ClearCalendar(accessToken, calendarID string) error
+	ListACLRules(accessToken, calendarID string) ([]models.ACLRule, error)
+	CreateACLRule(accessToken, calendarID string, rule models.CreateACLRule) (models.ACLRule, error)
+	UpdateACLRule(accessToken, calendarID, ruleID string, rule models.EditACLRule) (models.ACLRule, error)
+	DeleteACLRule(accessToken, calendarID, ruleID string) error
+	// ListEvents returns one page of events; query.PageToken selects the page.
+	ListEvents(accessToken string, query models.EventQuery) (models.EventList, error)
+	// SyncEvents returns one page of changes since query.SyncToken. Expired
+	// tokens fail with HTTP 410 Gone.
+	SyncEvents(accessToken string, query models.SyncQuery) (models.SyncResult, error)
+	GetEvent(accessToken, calendarID, eventID string) (*calendar.Event, error)
+	FreeBusy(accessToken string, query models.FreeBusyQuery) (map[string]models.CalendarBusy, error)
+	CreateEvent(accessToken, calendarID string, newEvent *calendar.Event, opts models.WriteOptions) (*calendar.Event, error)
+	UpdateEvent(accessToken, calendarID string, e calendar.Event, opts models.WriteOptions) error
+	// PatchEvent changes only the fields sent in patch.
+	PatchEvent(accessToken, calendarID, eventID string, patch *calendar.Event, opts models.WriteOptions) error
+	// MoveEvent changes an event's organizer calendar, keeping its ID.
+	MoveEvent(accessToken, calendarID, eventID, destinationID string, opts models.WriteOptions) (*calendar.Event, error)
+	DeleteEvent(accessToken, calendarID, eventID string, opts models.WriteOptions) error
+	// BatchEvents runs event writes in as few round trips as the provider
+	// allows and returns one result per op, in order.
+	BatchEvents(accessToken string, ops []EventOp) []EventOpResult
+}
+
+const (
+	BatchInsert = "insert"
+	BatchPatch  = "patch"
+	BatchDelete = "delete"
+)
+
+// EventOp is one write of a batch. Event is the new event for inserts and
+// the changed fields for patches.
+type EventOp struct {
+	Kind       string
+	CalendarID string
+	EventID    string
+	Event      *calendar.Event
+	Opts       models.WriteOptions
+}
+
+type EventOpResult struct {
+	// Event is the created event of an insert.
+	Event *calendar.Event
+	Err   error
+}
+
+var _ CalendarProvider = (*GoogleAdapter)(nil)
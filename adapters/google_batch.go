@@ -0,0 +1,188 @@
+package adapters
+
+import (
+	"backend/constants"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+)
+
+var errNoBatchResponse = errors.New("batch response is missing this request")
+
+// BatchEvents sends the ops through Google's multipart batch endpoint, up to
+// constants.MaxBatchRequests per HTTP call. When a call fails, the ops it
+// already has answers for keep them and only the others fail.
+func (a *GoogleAdapter) BatchEvents(accessToken string, ops []EventOp) []EventOpResult {
+	ctx := context.Background()
+	client := oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: accessToken}))
+
+	results := make([]EventOpResult, len(ops))
+	for start := 0; start < len(ops); start += constants.MaxBatchRequests {
+		end := min(start+constants.MaxBatchRequests, len(ops))
+		if err := a.sendBatch(ctx, client, ops[start:end], results[start:end]); err != nil {
+			for i := start; i < end; i++ {
+				if results[i].Err == errNoBatchResponse {
+					results[i].Err = err
+				}
+			}
+		}
+	}
+	return results
+}
+
+func (a *GoogleAdapter) sendBatch(ctx context.Context, client *http.Client, ops []EventOp, results []EventOpResult) error {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	for i, op := range ops {
+		results[i].Err = errNoBatchResponse
+		if err := writeBatchPart(w, i, op); err != nil {
+			results[i].Err = err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.batchURL, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "multipart/mixed; boundary="+w.Boundary())
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return err
+	}
+
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return fmt.Errorf("invalid batch response: %w", err)
+	}
+	r := multipart.NewReader(resp.Body, params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid batch response: %w", err)
+		}
+		i, ok := batchIndex(part.Header.Get("Content-ID"))
+		if !ok || i >= len(ops) || results[i].Err != errNoBatchResponse {
+			continue
+		}
+		results[i] = readBatchPart(part, ops[i])
+	}
+}
+
+// writeBatchPart writes op as an embedded HTTP request whose Content-ID
+// carries its index; Google answers with "response-" prepended to it.
+func writeBatchPart(w *multipart.Writer, i int, op EventOp) error {
+	method, path, err := batchRequestLine(op)
+	if err != nil {
+		return err
+	}
+	var payload []byte
+	if op.Kind != BatchDelete {
+		if payload, err = json.Marshal(op.Event); err != nil {
+			return err
+		}
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", "application/http")
+	header.Set("Content-ID", fmt.Sprintf("<item-%d>", i))
+	pw, err := w.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(pw, "%s %s HTTP/1.1\r\n", method, path)
+	if op.Opts.IfMatch != "" {
+		fmt.Fprintf(pw, "If-Match: %s\r\n", op.Opts.IfMatch)
+	}
+	if payload != nil {
+		fmt.Fprintf(pw, "Content-Type: application/json\r\nContent-Length: %d\r\n", len(payload))
+	}
+	fmt.Fprint(pw, "\r\n")
+	_, err = pw.Write(payload)
+	return err
+}
+
+func batchRequestLine(op EventOp) (string, string, error) {
+	path := "/calendar/v3/calendars/" + url.PathEscape(op.CalendarID) + "/events"
+	query := url.Values{}
+	if op.Opts.SendUpdates != "" {
+		query.Set("sendUpdates", op.Opts.SendUpdates)
+	}
+	if op.Event != nil && op.Event.ConferenceData != nil {
+		query.Set("conferenceDataVersion", "1")
+	}
+
+	var method string
+	switch op.Kind {
+	case BatchInsert:
+		method = http.MethodPost
+	case BatchPatch:
+		method = http.MethodPatch
+		path += "/" + url.PathEscape(op.EventID)
+	case BatchDelete:
+		method = http.MethodDelete
+		path += "/" + url.PathEscape(op.EventID)
+	default:
+		return "", "", fmt.Errorf("unknown batch operation %q", op.Kind)
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return method, path, nil
+}
+
+// batchIndex parses the index out of a "<response-item-N>" Content-ID.
+func batchIndex(contentID string) (int, bool) {
+	id := strings.Trim(contentID, "<>")
+	id, ok := strings.CutPrefix(id, "response-item-")
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(id)
+	return i, err == nil && i >= 0
+}
+
+func readBatchPart(part io.Reader, op EventOp) EventOpResult {
+	resp, err := http.ReadResponse(bufio.NewReader(part), nil)
+	if err != nil {
+		return EventOpResult{Err: fmt.Errorf("invalid batch response part: %w", err)}
+	}
+	defer resp.Body.Close()
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return EventOpResult{Err: err}
+	}
+	if op.Kind == BatchDelete {
+		return EventOpResult{}
+	}
+
+	var ev calendar.Event
+	if err := json.NewDecoder(resp.Body).Decode(&ev); err != nil {
+		return EventOpResult{Err: fmt.Errorf("invalid batch response part: %w", err)}
+	}
+	return EventOpResult{Event: &ev}
+}
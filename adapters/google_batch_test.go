@@ -0,0 +1,364 @@
+package adapters
+
+import (
+	"backend/models"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// batchRequest is one request embedded in a batch call.
+type batchRequest struct {
+	contentID string
+	method    string
+	uri       string
+	ifMatch   string
+	body      string
+}
+
+// batchAnswer is the embedded response for one request; a zero status leaves
+// the request unanswered.
+type batchAnswer struct {
+	status int
+	body   string
+}
+
+// fakeBatchServer stands in for Google's batch endpoint. answer decides the
+// response of every embedded request; truncate, when positive, cuts the
+// response off after that many parts.
+type fakeBatchServer struct {
+	t        *testing.T
+	answer   func(r batchRequest) batchAnswer
+	reverse  bool
+	truncate int
+
+	mu    sync.Mutex
+	calls [][]batchRequest
+	auth  []string
+}
+
+func (f *fakeBatchServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" || req.Method != http.MethodPost {
+		f.t.Errorf("unexpected batch call %s with content type %q", req.Method, req.Header.Get("Content-Type"))
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	var requests []batchRequest
+	r := multipart.NewReader(req.Body, params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.t.Fatalf("read batch part: %v", err)
+		}
+		if ct := part.Header.Get("Content-Type"); ct != "application/http" {
+			f.t.Errorf("part content type = %q", ct)
+		}
+		embedded, err := http.ReadRequest(bufio.NewReader(part))
+		if err != nil {
+			f.t.Fatalf("read embedded request: %v", err)
+		}
+		body, _ := io.ReadAll(embedded.Body)
+		requests = append(requests, batchRequest{
+			contentID: part.Header.Get("Content-ID"),
+			method:    embedded.Method,
+			uri:       embedded.RequestURI,
+			ifMatch:   embedded.Header.Get("If-Match"),
+			body:      string(body),
+		})
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, requests)
+	f.auth = append(f.auth, req.Header.Get("Authorization"))
+	f.mu.Unlock()
+
+	order := make([]int, len(requests))
+	for i := range order {
+		order[i] = i
+		if f.reverse {
+			order[i] = len(requests) - 1 - i
+		}
+	}
+
+	mw := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+	w.WriteHeader(http.StatusOK)
+	written := 0
+	for _, i := range order {
+		if f.truncate > 0 && written == f.truncate {
+			// Break off in the middle of the next part.
+			fmt.Fprintf(w, "\r\n--%s\r\nContent-Type: application/http\r\nContent-ID: <response-%s>\r\n\r\nHTTP/1.1 200 OK\r\nContent-Le",
+				mw.Boundary(), strings.Trim(requests[i].contentID, "<>"))
+			return
+		}
+		a := f.answer(requests[i])
+		if a.status == 0 {
+			continue
+		}
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", "application/http")
+		header.Set("Content-ID", "<response-"+strings.Trim(requests[i].contentID, "<>")+">")
+		pw, err := mw.CreatePart(header)
+		if err != nil {
+			f.t.Fatalf("write response part: %v", err)
+		}
+		fmt.Fprintf(pw, "HTTP/1.1 %d %s\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n%s",
+			a.status, http.StatusText(a.status), len(a.body), a.body)
+		written++
+	}
+	mw.Close()
+}
+
+// echoEvent answers every write with its own body plus an ID, and deletes
+// with 204.
+func echoEvent(r batchRequest) batchAnswer {
+	if r.method == http.MethodDelete {
+		return batchAnswer{status: http.StatusNoContent}
+	}
+	var ev calendar.Event
+	json.Unmarshal([]byte(r.body), &ev)
+	ev.Id = "id-" + ev.Summary
+	body, _ := json.Marshal(ev)
+	return batchAnswer{status: http.StatusOK, body: string(body)}
+}
+
+func newBatchTestAdapter(t *testing.T, f *fakeBatchServer) *GoogleAdapter {
+	f.t = t
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return &GoogleAdapter{batchURL: srv.URL}
+}
+
+func insertOps(n int) []EventOp {
+	ops := make([]EventOp, n)
+	for i := range ops {
+		ops[i] = EventOp{Kind: BatchInsert, CalendarID: "primary", Event: &calendar.Event{Summary: fmt.Sprint(i)}}
+	}
+	return ops
+}
+
+func statusCode(err error) int {
+	var gErr *googleapi.Error
+	if errors.As(err, &gErr) {
+		return gErr.Code
+	}
+	return 0
+}
+
+func TestBatchEventsEncodesRequests(t *testing.T) {
+	f := &fakeBatchServer{answer: echoEvent}
+	a := newBatchTestAdapter(t, f)
+
+	ops := []EventOp{
+		{
+			Kind:       BatchInsert,
+			CalendarID: "team calendar@group.calendar.google.com",
+			Event:      &calendar.Event{Summary: "standup", ConferenceData: &calendar.ConferenceData{}},
+			Opts:       models.WriteOptions{SendUpdates: "all"},
+		},
+		{
+			Kind:       BatchPatch,
+			CalendarID: "primary",
+			EventID:    "abc_20300107T090000Z",
+			Event:      &calendar.Event{Location: "Room 1"},
+			Opts:       models.WriteOptions{IfMatch: `"42"`},
+		},
+		{Kind: BatchDelete, CalendarID: "primary", EventID: "gone", Opts: models.WriteOptions{SendUpdates: "none"}},
+	}
+	results := a.BatchEvents("token-1", ops)
+
+	if len(f.calls) != 1 {
+		t.Fatalf("got %d batch calls, want 1", len(f.calls))
+	}
+	if f.auth[0] != "Bearer token-1" {
+		t.Errorf("Authorization = %q", f.auth[0])
+	}
+
+	want := []batchRequest{
+		{
+			contentID: "<item-0>",
+			method:    http.MethodPost,
+			uri:       "/calendar/v3/calendars/team%20calendar@group.calendar.google.com/events?conferenceDataVersion=1&sendUpdates=all",
+		},
+		{contentID: "<item-1>", method: http.MethodPatch, uri: "/calendar/v3/calendars/primary/events/abc_20300107T090000Z", ifMatch: `"42"`},
+		{contentID: "<item-2>", method: http.MethodDelete, uri: "/calendar/v3/calendars/primary/events/gone?sendUpdates=none"},
+	}
+	got := f.calls[0]
+	if len(got) != len(want) {
+		t.Fatalf("got %d embedded requests, want %d", len(got), len(want))
+	}
+	for i := range want {
+		g := got[i]
+		g.body = ""
+		if g != want[i] {
+			t.Errorf("request %d = %+v, want %+v", i, g, want[i])
+		}
+	}
+	if !strings.Contains(got[0].body, `"summary":"standup"`) || !strings.Contains(got[1].body, `"location":"Room 1"`) {
+		t.Errorf("unexpected bodies %q and %q", got[0].body, got[1].body)
+	}
+	if got[2].body != "" {
+		t.Errorf("delete body = %q, want empty", got[2].body)
+	}
+
+	for i, r := range results {
+		if r.Err != nil {
+			t.Errorf("result %d: %v", i, r.Err)
+		}
+	}
+	if results[0].Event == nil || results[0].Event.Id != "id-standup" {
+		t.Errorf("insert result = %+v", results[0].Event)
+	}
+	if results[2].Event != nil {
+		t.Errorf("delete result carries an event: %+v", results[2].Event)
+	}
+}
+
+func TestBatchEventsMapsResponsesByContentID(t *testing.T) {
+	f := &fakeBatchServer{answer: echoEvent, reverse: true}
+	a := newBatchTestAdapter(t, f)
+
+	results := a.BatchEvents("token", insertOps(5))
+	for i, r := range results {
+		if r.Err != nil {
+			t.Fatalf("result %d: %v", i, r.Err)
+		}
+		if want := fmt.Sprintf("id-%d", i); r.Event.Id != want {
+			t.Errorf("result %d has event %q, want %q", i, r.Event.Id, want)
+		}
+	}
+}
+
+func TestBatchEventsPerPartErrors(t *testing.T) {
+	f := &fakeBatchServer{answer: func(r batchRequest) batchAnswer {
+		switch {
+		case strings.HasSuffix(r.uri, "/missing"):
+			return batchAnswer{status: http.StatusNotFound, body: `{"error":{"code":404,"message":"Not Found"}}`}
+		case r.ifMatch != "":
+			return batchAnswer{status: http.StatusPreconditionFailed, body: `{"error":{"code":412,"message":"Precondition Failed"}}`}
+		}
+		return echoEvent(r)
+	}}
+	a := newBatchTestAdapter(t, f)
+
+	results := a.BatchEvents("token", []EventOp{
+		{Kind: BatchInsert, CalendarID: "primary", Event: &calendar.Event{Summary: "ok"}},
+		{Kind: BatchDelete, CalendarID: "primary", EventID: "missing"},
+		{Kind: BatchPatch, CalendarID: "primary", EventID: "stale", Event: &calendar.Event{}, Opts: models.WriteOptions{IfMatch: `"1"`}},
+		{Kind: BatchDelete, CalendarID: "primary", EventID: "present"},
+	})
+
+	if results[0].Err != nil || results[0].Event.Id != "id-ok" {
+		t.Errorf("result 0 = %+v", results[0])
+	}
+	if code := statusCode(results[1].Err); code != http.StatusNotFound {
+		t.Errorf("result 1 error = %v, want 404", results[1].Err)
+	}
+	if code := statusCode(results[2].Err); code != http.StatusPreconditionFailed {
+		t.Errorf("result 2 error = %v, want 412", results[2].Err)
+	}
+	if results[3].Err != nil {
+		t.Errorf("result 3 error = %v", results[3].Err)
+	}
+}
+
+func TestBatchEventsChunks(t *testing.T) {
+	f := &fakeBatchServer{answer: echoEvent}
+	a := newBatchTestAdapter(t, f)
+
+	results := a.BatchEvents("token", insertOps(120))
+
+	var sizes []int
+	for _, call := range f.calls {
+		sizes = append(sizes, len(call))
+	}
+	if fmt.Sprint(sizes) != "[50 50 20]" {
+		t.Errorf("batch sizes = %v, want [50 50 20]", sizes)
+	}
+	for i, r := range results {
+		if r.Err != nil || r.Event.Id != fmt.Sprintf("id-%d", i) {
+			t.Fatalf("result %d = %+v", i, r)
+		}
+	}
+}
+
+func TestBatchEventsPartialResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		server   *fakeBatchServer
+		answered int
+	}{
+		{
+			name:     "truncated body",
+			server:   &fakeBatchServer{answer: echoEvent, truncate: 2},
+			answered: 2,
+		},
+		{
+			name: "missing parts",
+			server: &fakeBatchServer{answer: func(r batchRequest) batchAnswer {
+				if r.contentID == "<item-2>" || r.contentID == "<item-3>" {
+					return batchAnswer{}
+				}
+				return echoEvent(r)
+			}},
+			answered: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newBatchTestAdapter(t, tt.server)
+			results := a.BatchEvents("token", insertOps(4))
+			for i, r := range results {
+				if i < tt.answered {
+					if r.Err != nil || r.Event == nil || r.Event.Id != fmt.Sprintf("id-%d", i) {
+						t.Errorf("answered result %d = %+v", i, r)
+					}
+					continue
+				}
+				if r.Err == nil {
+					t.Errorf("unanswered result %d has no error", i)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchEventsCallFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error":{"code":503,"message":"Backend Error"}}`)
+	}))
+	defer srv.Close()
+	a := &GoogleAdapter{batchURL: srv.URL}
+
+	results := a.BatchEvents("token", append(insertOps(2), EventOp{Kind: "replace"}))
+	for i, r := range results[:2] {
+		if code := statusCode(r.Err); code != http.StatusServiceUnavailable {
+			t.Errorf("result %d error = %v, want 503", i, r.Err)
+		}
+	}
+	// The op that could not be encoded keeps its own error.
+	if r := results[2]; r.Err == nil || statusCode(r.Err) != 0 {
+		t.Errorf("result 2 error = %v, want an encoding error", r.Err)
+	}
+}
@@ -1,83 +1,369 @@
 package adapters
 
 import (
+	"backend/constants"
 	"backend/models"
 	"backend/utils"
 	"context"
 	"fmt"
-	"sort"
+	"time"
 
 	"golang.org/x/oauth2"
 	"google.golang.org/api/calendar/v3"
 	"google.golang.org/api/option"
 )
 
-type GoogleAdapter struct{}
+type GoogleAdapter struct {
+	// batchURL is the multipart batch endpoint used by BatchEvents.
+	batchURL string
+	// apiURL overrides the Calendar API endpoint when set.
+	apiURL string
+}
 
 func NewGoogleAdapter() *GoogleAdapter {
-	return &GoogleAdapter{}
+	return &GoogleAdapter{batchURL: constants.GoogleBatchURL}
 }
 
 func (a *GoogleAdapter) newClient(ctx context.Context, accessToken string) (*calendar.Service, error) {
 	token := &oauth2.Token{AccessToken: accessToken}
 	client := oauth2.NewClient(ctx, oauth2.StaticTokenSource(token))
-	return calendar.NewService(ctx, option.WithHTTPClient(client))
+	opts := []option.ClientOption{option.WithHTTPClient(client)}
+	if a.apiURL != "" {
+		opts = append(opts, option.WithEndpoint(a.apiURL))
+	}
+	return calendar.NewService(ctx, opts...)
+}
+
+func (a *GoogleAdapter) ListCalendars(accessToken string) ([]models.Calendar, error) {
+	ctx := context.Background()
+	srv, err := a.newClient(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var calendars []models.Calendar
+	err = srv.CalendarList.List().Pages(ctx, func(resp *calendar.CalendarList) error {
+		for _, c := range resp.Items {
+			calendars = append(calendars, utils.ToCalendar(c))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return calendars, nil
+}
+
+func (a *GoogleAdapter) CreateCalendar(accessToken string, c models.CreateCalendar) (models.Calendar, error) {
+	ctx := context.Background()
+	srv, err := a.newClient(ctx, accessToken)
+	if err != nil {
+		return models.Calendar{}, err
+	}
+
+	created, err := srv.Calendars.Insert(&calendar.Calendar{
+		Summary:     c.Summary,
+		Description: c.Description,
+		TimeZone:    c.TimeZone,
+	}).Do()
+	if err != nil {
+		return models.Calendar{}, err
+	}
+	return utils.ToOwnedCalendar(created), nil
+}
+
+func (a *GoogleAdapter) PatchCalendar(accessToken, calendarID string, c models.EditCalendar) (models.Calendar, error) {
+	ctx := context.Background()
+	srv, err := a.newClient(ctx, accessToken)
+	if err != nil {
+		return models.Calendar{}, err
+	}
+
+	patch := &calendar.Calendar{}
+	if c.Summary != nil {
+		patch.Summary = *c.Summary
+	}
+	if c.Description != nil {
+		patch.Description = *c.Description
+		// Empty strings are omitted unless forced, which would keep the old value.
+		patch.ForceSendFields = append(patch.ForceSendFields, "Description")
+	}
+	if c.TimeZone != nil {
+		patch.TimeZone = *c.TimeZone
+	}
+
+	updated, err := srv.Calendars.Patch(calendarID, patch).Do()
+	if err != nil {
+		return models.Calendar{}, err
+	}
+	return utils.ToOwnedCalendar(updated), nil
+}
+
+func (a *GoogleAdapter) DeleteCalendar(accessToken, calendarID string) error {
+	ctx := context.Background()
+	srv, err := a.newClient(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+	return srv.Calendars.Delete(calendarID).Do()
+}
+
+// ClearCalendar removes every event from a calendar. Google only offers a
+// clear call for the primary calendar, so secondary calendars are emptied
+// event by event.
+func (a *GoogleAdapter) ClearCalendar(accessToken, calendarID string) error {
+	ctx := context.Background()
+	srv, err := a.newClient(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+
+	if calendarID == constants.PrimaryCalendarID {
+		return srv.Calendars.Clear(calendarID).Do()
+	}
+
+	var eventIDs []string
+	err = srv.Events.List(calendarID).Fields("nextPageToken", "items(id)").Pages(ctx, func(resp *calendar.Events) error {
+		for _, e := range resp.Items {
+			eventIDs = append(eventIDs, e.Id)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range eventIDs {
+		if err := srv.Events.Delete(calendarID, id).Do(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (a *GoogleAdapter) ListEvents(accessToken string, query models.EventQuery) ([]models.Event, error) {
+func (a *GoogleAdapter) ListACLRules(accessToken, calendarID string) ([]models.ACLRule, error) {
 	ctx := context.Background()
 	srv, err := a.newClient(ctx, accessToken)
 	if err != nil {
 		return nil, err
 	}
 
-	call := srv.Events.List(query.CalendarID).MaxResults(20).
+	var rules []models.ACLRule
+	err = srv.Acl.List(calendarID).Pages(ctx, func(resp *calendar.Acl) error {
+		for _, r := range resp.Items {
+			rules = append(rules, utils.ToACLRule(r))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (a *GoogleAdapter) CreateACLRule(accessToken, calendarID string, rule models.CreateACLRule) (models.ACLRule, error) {
+	ctx := context.Background()
+	srv, err := a.newClient(ctx, accessToken)
+	if err != nil {
+		return models.ACLRule{}, err
+	}
+
+	created, err := srv.Acl.Insert(calendarID, &calendar.AclRule{
+		Role: rule.Role,
+		Scope: &calendar.AclRuleScope{
+			Type:  rule.ScopeType,
+			Value: rule.ScopeValue,
+		},
+	}).SendNotifications(rule.SendNotifications).Do()
+	if err != nil {
+		return models.ACLRule{}, err
+	}
+	return utils.ToACLRule(created), nil
+}
+
+func (a *GoogleAdapter) UpdateACLRule(accessToken, calendarID, ruleID string, rule models.EditACLRule) (models.ACLRule, error) {
+	ctx := context.Background()
+	srv, err := a.newClient(ctx, accessToken)
+	if err != nil {
+		return models.ACLRule{}, err
+	}
+
+	updated, err := srv.Acl.Patch(calendarID, ruleID, &calendar.AclRule{Role: rule.Role}).Do()
+	if err != nil {
+		return models.ACLRule{}, err
+	}
+	return utils.ToACLRule(updated), nil
+}
+
+func (a *GoogleAdapter) DeleteACLRule(accessToken, calendarID, ruleID string) error {
+	ctx := context.Background()
+	srv, err := a.newClient(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+	return srv.Acl.Delete(calendarID, ruleID).Do()
+}
+
+func (a *GoogleAdapter) ListEvents(accessToken string, query models.EventQuery) (models.EventList, error) {
+	ctx := context.Background()
+	srv, err := a.newClient(ctx, accessToken)
+	if err != nil {
+		return models.EventList{}, err
+	}
+
+	call := srv.Events.List(query.CalendarID).MaxResults(int64(query.PageSize)).
 		TimeMin(query.From).
 		TimeMax(query.To)
+	if query.PageToken != "" {
+		call = call.PageToken(query.PageToken)
+	}
+	if query.TimeZone != "" {
+		call = call.TimeZone(query.TimeZone)
+	}
+	if query.SingleEvents {
+		call = call.SingleEvents(true).OrderBy(constants.OrderByStartTime)
+	}
+	if query.OrderBy != "" {
+		call = call.OrderBy(query.OrderBy)
+	}
+	if query.Q != "" {
+		call = call.Q(query.Q)
+	}
+	if query.EventType != "" {
+		call = call.EventTypes(query.EventType)
+	}
+	if query.UpdatedMin != "" {
+		call = call.UpdatedMin(query.UpdatedMin)
+	}
+	if query.Status == constants.EventStatusCancelled {
+		call = call.ShowDeleted(true)
+	}
 
 	resp, err := call.Do()
 	if err != nil {
 		fmt.Println(err)
-		return nil, err
+		return models.EventList{}, err
 	}
 
-	var eventList []models.Event
+	// Google has no attendee, location or status filter, so those are
+	// applied to the page here; filtered pages can be shorter than asked.
+	// Q is left to Google, which searches more fields than we can. With
+	// updatedMin set Google also returns deleted events, which are only kept
+	// when cancelled events were asked for.
+	filter := query
+	filter.Q = ""
+	eventList := []models.Event{}
 	for _, i := range resp.Items {
-		start := utils.ParseDateTime(i.Start)
-		end := utils.ParseDateTime(i.End)
-		eventList = append(eventList, models.Event{
-			ID:          i.Id,
-			Summary:     i.Summary,
-			Description: i.Description,
-			Location:    i.Location,
-			StartTime:   start,
-			EndTime:     end,
-		})
-	}
-	sort.Slice(eventList, func(i, j int) bool {
-		return eventList[i].StartTime.Before(eventList[j].StartTime)
-	})
+		ev := utils.ToEvent(i)
+		if ev.Status == constants.EventStatusCancelled && query.Status != constants.EventStatusCancelled {
+			continue
+		}
+		if utils.MatchesEventQuery(ev, filter) {
+			eventList = append(eventList, ev)
+		}
+	}
+	utils.SortEventsBy(eventList, query.OrderBy)
 
-	return eventList, nil
+	return models.EventList{Events: eventList, NextPageToken: resp.NextPageToken}, nil
 }
 
-func (a *GoogleAdapter) CreateEvent(accessToken string, newEvent *calendar.Event) error {
+func (a *GoogleAdapter) SyncEvents(accessToken string, query models.SyncQuery) (models.SyncResult, error) {
 	ctx := context.Background()
 	srv, err := a.newClient(ctx, accessToken)
 	if err != nil {
-		return err
+		return models.SyncResult{}, err
+	}
+
+	// Sync calls may not carry filters such as timeMin, only the tokens.
+	call := srv.Events.List(query.CalendarID).MaxResults(constants.MaxEventPageSize)
+	if query.SyncToken != "" {
+		call = call.SyncToken(query.SyncToken)
+	}
+	if query.PageToken != "" {
+		call = call.PageToken(query.PageToken)
+	}
+
+	resp, err := call.Do()
+	if err != nil {
+		return models.SyncResult{}, err
 	}
 
-	calendarID := "primary"
+	result := models.SyncResult{
+		Events:        []models.Event{},
+		FullSync:      query.SyncToken == "",
+		NextPageToken: resp.NextPageToken,
+		NextSyncToken: resp.NextSyncToken,
+	}
+	for _, i := range resp.Items {
+		result.Events = append(result.Events, utils.ToEvent(i))
+	}
+	return result, nil
+}
+
+func (a *GoogleAdapter) GetEvent(accessToken, calendarID, eventID string) (*calendar.Event, error) {
+	ctx := context.Background()
+	srv, err := a.newClient(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Events.Get(calendarID, eventID).Do()
+}
 
-	_, err = srv.Events.Insert(calendarID, newEvent).Do()
+func (a *GoogleAdapter) FreeBusy(accessToken string, query models.FreeBusyQuery) (map[string]models.CalendarBusy, error) {
+	ctx := context.Background()
+	srv, err := a.newClient(ctx, accessToken)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+
+	req := &calendar.FreeBusyRequest{
+		TimeMin:  query.From.Format(time.RFC3339),
+		TimeMax:  query.To.Format(time.RFC3339),
+		TimeZone: query.TimeZone,
+	}
+	for _, id := range query.Calendars {
+		req.Items = append(req.Items, &calendar.FreeBusyRequestItem{Id: id})
+	}
+
+	resp, err := srv.Freebusy.Query(req).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]models.CalendarBusy{}
+	for id, c := range resp.Calendars {
+		busy := models.CalendarBusy{Busy: []models.TimeRange{}}
+		for _, p := range c.Busy {
+			start, _ := time.Parse(time.RFC3339, p.Start)
+			end, _ := time.Parse(time.RFC3339, p.End)
+			busy.Busy = append(busy.Busy, models.TimeRange{Start: start, End: end})
+		}
+		for _, e := range c.Errors {
+			busy.Errors = append(busy.Errors, e.Reason)
+		}
+		result[id] = busy
+	}
+	return result, nil
+}
+
+func (a *GoogleAdapter) CreateEvent(accessToken, calendarID string, newEvent *calendar.Event, opts models.WriteOptions) (*calendar.Event, error) {
+	ctx := context.Background()
+	srv, err := a.newClient(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	call := srv.Events.Insert(calendarID, newEvent)
+	if opts.SendUpdates != "" {
+		call = call.SendUpdates(opts.SendUpdates)
+	}
+	if newEvent.ConferenceData != nil {
+		call = call.ConferenceDataVersion(1)
+	}
+	return call.Do()
 }
 
-func (a *GoogleAdapter) UpdateEvent(accessToken string, e calendar.Event) error {
+func (a *GoogleAdapter) UpdateEvent(accessToken, calendarID string, e calendar.Event, opts models.WriteOptions) error {
 	ctx := context.Background()
 
 	srv, err := a.newClient(ctx, accessToken)
@@ -85,15 +371,68 @@ func (a *GoogleAdapter) UpdateEvent(accessToken string, e calendar.Event) error
 		return err
 	}
 
-	_, err = srv.Events.Update("primary", e.Id, &e).Do()
+	call := srv.Events.Update(calendarID, e.Id, &e)
+	if opts.SendUpdates != "" {
+		call = call.SendUpdates(opts.SendUpdates)
+	}
+	if opts.IfMatch != "" {
+		call.Header().Set("If-Match", opts.IfMatch)
+	}
+	// Without a version Google keeps the existing conference untouched.
+	if e.ConferenceData != nil {
+		call = call.ConferenceDataVersion(1)
+	}
+	_, err = call.Do()
+	return err
+}
+
+func (a *GoogleAdapter) PatchEvent(accessToken, calendarID, eventID string, patch *calendar.Event, opts models.WriteOptions) error {
+	ctx := context.Background()
+	srv, err := a.newClient(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+
+	call := srv.Events.Patch(calendarID, eventID, patch)
+	if opts.SendUpdates != "" {
+		call = call.SendUpdates(opts.SendUpdates)
+	}
+	if opts.IfMatch != "" {
+		call.Header().Set("If-Match", opts.IfMatch)
+	}
+	if patch.ConferenceData != nil {
+		call = call.ConferenceDataVersion(1)
+	}
+	_, err = call.Do()
 	return err
 }
 
-func (a *GoogleAdapter) DeleteEvent(accessToken, eventID string) error {
+func (a *GoogleAdapter) MoveEvent(accessToken, calendarID, eventID, destinationID string, opts models.WriteOptions) (*calendar.Event, error) {
+	ctx := context.Background()
+	srv, err := a.newClient(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	call := srv.Events.Move(calendarID, eventID, destinationID)
+	if opts.SendUpdates != "" {
+		call = call.SendUpdates(opts.SendUpdates)
+	}
+	return call.Do()
+}
+
+func (a *GoogleAdapter) DeleteEvent(accessToken, calendarID, eventID string, opts models.WriteOptions) error {
 	ctx := context.Background()
 	srv, err := a.newClient(ctx, accessToken)
 	if err != nil {
 		return err
 	}
-	return srv.Events.Delete("primary", eventID).Do()
+	call := srv.Events.Delete(calendarID, eventID)
+	if opts.SendUpdates != "" {
+		call = call.SendUpdates(opts.SendUpdates)
+	}
+	if opts.IfMatch != "" {
+		call.Header().Set("If-Match", opts.IfMatch)
+	}
+	return call.Do()
 }
@@ -0,0 +1,108 @@
+package adapters
+
+import (
+	"backend/constants"
+	"backend/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// newListTestAdapter serves items for every events list call and records the
+// query parameters of the last one.
+func newListTestAdapter(t *testing.T, items []*calendar.Event, params *url.Values) *GoogleAdapter {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*params = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&calendar.Events{Items: items})
+	}))
+	t.Cleanup(srv.Close)
+	return &GoogleAdapter{apiURL: srv.URL + "/"}
+}
+
+func listedIDs(list models.EventList) []string {
+	ids := []string{}
+	for _, e := range list.Events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestGoogleListEventsHidesDeletedEvents(t *testing.T) {
+	start := &calendar.EventDateTime{DateTime: "2030-01-07T09:00:00Z"}
+	end := &calendar.EventDateTime{DateTime: "2030-01-07T10:00:00Z"}
+	items := []*calendar.Event{
+		{Id: "live", Status: constants.EventStatusConfirmed, Start: start, End: end, Updated: "2030-01-02T00:00:00Z"},
+		{Id: "deleted", Status: constants.EventStatusCancelled, Updated: "2030-01-02T00:00:00Z"},
+	}
+
+	tests := []struct {
+		name      string
+		status    string
+		want      []string
+		wantShown string
+	}{
+		{name: "no status filter", want: []string{"live"}},
+		{name: "cancelled asked for", status: constants.EventStatusCancelled, want: []string{"deleted"}, wantShown: "true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params url.Values
+			a := newListTestAdapter(t, items, &params)
+			list, err := a.ListEvents("token", models.EventQuery{
+				CalendarID: constants.PrimaryCalendarID,
+				From:       "2030-01-01T00:00:00Z",
+				To:         "2030-02-01T00:00:00Z",
+				UpdatedMin: "2030-01-01T00:00:00Z",
+				Status:     tt.status,
+				PageSize:   10,
+			})
+			if err != nil {
+				t.Fatalf("ListEvents: %v", err)
+			}
+			if got := listedIDs(list); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+			if params.Get("updatedMin") != "2030-01-01T00:00:00Z" || params.Get("showDeleted") != tt.wantShown {
+				t.Errorf("query = %v", params)
+			}
+		})
+	}
+}
+
+func TestGoogleListEventsLeavesTextSearchToGoogle(t *testing.T) {
+	start := &calendar.EventDateTime{DateTime: "2030-01-07T09:00:00Z"}
+	end := &calendar.EventDateTime{DateTime: "2030-01-07T10:00:00Z"}
+	// Google matched these on the organizer, which the local filters do not
+	// search; only the location filter applies here.
+	items := []*calendar.Event{
+		{Id: "a", Summary: "Planning", Location: "Room 1", Start: start, End: end, Organizer: &calendar.EventOrganizer{Email: "carol@example.com"}},
+		{Id: "b", Summary: "Review", Location: "Room 2", Start: start, End: end, Organizer: &calendar.EventOrganizer{Email: "carol@example.com"}},
+	}
+
+	var params url.Values
+	a := newListTestAdapter(t, items, &params)
+	list, err := a.ListEvents("token", models.EventQuery{
+		CalendarID: constants.PrimaryCalendarID,
+		From:       "2030-01-01T00:00:00Z",
+		To:         "2030-02-01T00:00:00Z",
+		Q:          "carol",
+		Location:   "room 1",
+		PageSize:   10,
+	})
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if params.Get("q") != "carol" {
+		t.Errorf("q = %q, want it sent to Google", params.Get("q"))
+	}
+	if got := listedIDs(list); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("events = %v, want [a]", got)
+	}
+}
@@ -0,0 +1,747 @@
+package adapters
+
+import (
+	"backend/constants"
+	"backend/models"
+	"backend/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	statusConfirmed = "confirmed"
+	statusCancelled = "cancelled"
+)
+
+// MemoryAdapter is an in-memory CalendarProvider for tests and offline
+// development. All users share the same store and access tokens are ignored.
+//
+// Recurring series are stored once; edited or cancelled instances are stored
+// as separate events pointing at their master, as Google does.
+type MemoryAdapter struct {
+	mu        sync.RWMutex
+	calendars map[string]*calendar.CalendarListEntry
+	acl       map[string]map[string]*calendar.AclRule // calendar ID -> rule ID -> rule
+	events    map[string]map[string]*calendar.Event   // calendar ID -> event ID -> event
+	nextID    int
+
+	// Sync bookkeeping: every change gets the next sequence number, and sync
+	// tokens are the sequence number at the time of the sync.
+	seq       int
+	changes   map[string]map[string]int             // calendar ID -> event ID -> last change
+	deleted   map[string]map[string]*calendar.Event // calendar ID -> event ID -> tombstone
+	syncFloor map[string]int                        // tokens below this have expired
+}
+
+var _ CalendarProvider = (*MemoryAdapter)(nil)
+
+func NewMemoryAdapter() *MemoryAdapter {
+	a := &MemoryAdapter{
+		calendars: map[string]*calendar.CalendarListEntry{},
+		acl:       map[string]map[string]*calendar.AclRule{},
+		events:    map[string]map[string]*calendar.Event{},
+		changes:   map[string]map[string]int{},
+		deleted:   map[string]map[string]*calendar.Event{},
+		syncFloor: map[string]int{},
+	}
+	a.addCalendar(&calendar.CalendarListEntry{
+		Id:         constants.PrimaryCalendarID,
+		Summary:    "Primary",
+		AccessRole: "owner",
+		TimeZone:   constants.DefaultTimeZone,
+		Primary:    true,
+	})
+	return a
+}
+
+func (a *MemoryAdapter) addCalendar(entry *calendar.CalendarListEntry) {
+	a.calendars[entry.Id] = entry
+	a.acl[entry.Id] = map[string]*calendar.AclRule{}
+	a.events[entry.Id] = map[string]*calendar.Event{}
+	a.changes[entry.Id] = map[string]int{}
+	a.deleted[entry.Id] = map[string]*calendar.Event{}
+}
+
+// touch records a change to an event for SyncEvents.
+func (a *MemoryAdapter) touch(calendarID, eventID string) {
+	a.seq++
+	a.changes[calendarID][eventID] = a.seq
+	if e, ok := a.events[calendarID][eventID]; ok {
+		e.Etag = fmt.Sprintf(`"%d"`, a.seq)
+		e.Updated = time.Now().UTC().Format(time.RFC3339)
+	}
+}
+
+func (a *MemoryAdapter) ListCalendars(accessToken string) ([]models.Calendar, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var calendars []models.Calendar
+	for _, c := range a.calendars {
+		calendars = append(calendars, utils.ToCalendar(c))
+	}
+	sort.Slice(calendars, func(i, j int) bool {
+		if calendars[i].Primary != calendars[j].Primary {
+			return calendars[i].Primary
+		}
+		return calendars[i].Summary < calendars[j].Summary
+	})
+	return calendars, nil
+}
+
+func (a *MemoryAdapter) CreateCalendar(accessToken string, c models.CreateCalendar) (models.Calendar, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.nextID++
+	entry := &calendar.CalendarListEntry{
+		Id:          fmt.Sprintf("mem%d@group.calendar.memory", a.nextID),
+		Summary:     c.Summary,
+		Description: c.Description,
+		TimeZone:    c.TimeZone,
+		AccessRole:  "owner",
+	}
+	a.addCalendar(entry)
+	return utils.ToCalendar(entry), nil
+}
+
+func (a *MemoryAdapter) PatchCalendar(accessToken, calendarID string, c models.EditCalendar) (models.Calendar, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry, ok := a.calendars[calendarID]
+	if !ok {
+		return models.Calendar{}, errNotFound()
+	}
+	if c.Summary != nil {
+		entry.Summary = *c.Summary
+	}
+	if c.Description != nil {
+		entry.Description = *c.Description
+	}
+	if c.TimeZone != nil {
+		entry.TimeZone = *c.TimeZone
+	}
+	return utils.ToCalendar(entry), nil
+}
+
+func (a *MemoryAdapter) DeleteCalendar(accessToken, calendarID string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry, ok := a.calendars[calendarID]
+	if !ok {
+		return errNotFound()
+	}
+	if entry.Primary {
+		return &googleapi.Error{Code: http.StatusBadRequest, Message: "Cannot delete primary calendar"}
+	}
+	delete(a.calendars, calendarID)
+	delete(a.acl, calendarID)
+	delete(a.events, calendarID)
+	delete(a.changes, calendarID)
+	delete(a.deleted, calendarID)
+	delete(a.syncFloor, calendarID)
+	return nil
+}
+
+func (a *MemoryAdapter) ClearCalendar(accessToken, calendarID string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.calendars[calendarID]; !ok {
+		return errNotFound()
+	}
+	// Clearing expires every sync token issued for the calendar so far.
+	a.events[calendarID] = map[string]*calendar.Event{}
+	a.changes[calendarID] = map[string]int{}
+	a.deleted[calendarID] = map[string]*calendar.Event{}
+	a.syncFloor[calendarID] = a.seq + 1
+	return nil
+}
+
+func (a *MemoryAdapter) ListACLRules(accessToken, calendarID string) ([]models.ACLRule, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	rules, ok := a.acl[calendarID]
+	if !ok {
+		return nil, errNotFound()
+	}
+
+	var ruleList []models.ACLRule
+	for _, r := range rules {
+		ruleList = append(ruleList, utils.ToACLRule(r))
+	}
+	sort.Slice(ruleList, func(i, j int) bool { return ruleList[i].ID < ruleList[j].ID })
+	return ruleList, nil
+}
+
+func (a *MemoryAdapter) CreateACLRule(accessToken, calendarID string, rule models.CreateACLRule) (models.ACLRule, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rules, ok := a.acl[calendarID]
+	if !ok {
+		return models.ACLRule{}, errNotFound()
+	}
+
+	// Google derives rule IDs from the scope, so granting twice updates the rule.
+	r := &calendar.AclRule{
+		Id:   rule.ScopeType + ":" + rule.ScopeValue,
+		Role: rule.Role,
+		Scope: &calendar.AclRuleScope{
+			Type:  rule.ScopeType,
+			Value: rule.ScopeValue,
+		},
+	}
+	rules[r.Id] = r
+	return utils.ToACLRule(r), nil
+}
+
+func (a *MemoryAdapter) UpdateACLRule(accessToken, calendarID, ruleID string, rule models.EditACLRule) (models.ACLRule, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	r, ok := a.acl[calendarID][ruleID]
+	if !ok {
+		return models.ACLRule{}, errNotFound()
+	}
+	r.Role = rule.Role
+	return utils.ToACLRule(r), nil
+}
+
+func (a *MemoryAdapter) DeleteACLRule(accessToken, calendarID, ruleID string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.acl[calendarID][ruleID]; !ok {
+		return errNotFound()
+	}
+	delete(a.acl[calendarID], ruleID)
+	return nil
+}
+
+// ListEvents pages through the sorted matches; page tokens are offsets.
+func (a *MemoryAdapter) ListEvents(accessToken string, query models.EventQuery) (models.EventList, error) {
+	from, err := parseBound(query.From)
+	if err != nil {
+		return models.EventList{}, err
+	}
+	to, err := parseBound(query.To)
+	if err != nil {
+		return models.EventList{}, err
+	}
+	offset := 0
+	if query.PageToken != "" {
+		offset, err = strconv.Atoi(query.PageToken)
+		if err != nil || offset < 0 {
+			return models.EventList{}, &googleapi.Error{Code: http.StatusBadRequest, Message: "Invalid page token"}
+		}
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	events, ok := a.events[query.CalendarID]
+	if !ok {
+		return models.EventList{}, errNotFound()
+	}
+	var eventList []models.Event
+	if query.Status == statusCancelled {
+		eventList = cancelledEvents(events, from, to)
+	} else {
+		eventList = matchEvents(events, from, to, query.SingleEvents)
+	}
+	filtered := []models.Event{}
+	for _, ev := range eventList {
+		if utils.MatchesEventQuery(ev, query) {
+			filtered = append(filtered, ev)
+		}
+	}
+	eventList = filtered
+	utils.SortEventsBy(eventList, query.OrderBy)
+
+	page := models.EventList{Events: []models.Event{}}
+	if offset < len(eventList) {
+		page.Events = eventList[offset:]
+	}
+	if query.PageSize > 0 && len(page.Events) > query.PageSize {
+		page.Events = page.Events[:query.PageSize]
+		page.NextPageToken = strconv.Itoa(offset + query.PageSize)
+	}
+	return page, nil
+}
+
+// matchEvents returns the sorted events of a calendar overlapping [from, to).
+func matchEvents(events map[string]*calendar.Event, from, to time.Time, singleEvents bool) []models.Event {
+	eventList := []models.Event{}
+	for _, e := range events {
+		if e.Status == statusCancelled {
+			continue
+		}
+
+		if len(e.Recurrence) > 0 {
+			instances, err := expandInstances(events, e, from, to)
+			if err != nil {
+				// Rules we cannot expand are listed as a single master event.
+				if overlaps(utils.ToEvent(e), from, to) {
+					eventList = append(eventList, utils.ToEvent(e))
+				}
+				continue
+			}
+			if singleEvents {
+				eventList = append(eventList, instances...)
+			} else if len(instances) > 0 {
+				eventList = append(eventList, utils.ToEvent(e))
+			}
+			continue
+		}
+
+		// Edited instances are emitted by expandInstances when expanding.
+		if singleEvents && e.RecurringEventId != "" {
+			if _, ok := events[e.RecurringEventId]; ok {
+				continue
+			}
+		}
+
+		ev := utils.ToEvent(e)
+		if overlaps(ev, from, to) {
+			eventList = append(eventList, ev)
+		}
+	}
+	utils.SortEvents(eventList)
+	return eventList
+}
+
+// cancelledEvents returns the cancelled instances overlapping [from, to);
+// deleted events themselves are only kept as sync tombstones.
+func cancelledEvents(events map[string]*calendar.Event, from, to time.Time) []models.Event {
+	eventList := []models.Event{}
+	for _, e := range events {
+		ev := utils.ToEvent(e)
+		if e.Status == statusCancelled && overlaps(ev, from, to) {
+			eventList = append(eventList, ev)
+		}
+	}
+	utils.SortEvents(eventList)
+	return eventList
+}
+
+// FreeBusy reports every event as busy time; unknown calendars get a
+// "notFound" error entry like Google returns.
+func (a *MemoryAdapter) FreeBusy(accessToken string, query models.FreeBusyQuery) (map[string]models.CalendarBusy, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	result := map[string]models.CalendarBusy{}
+	for _, id := range query.Calendars {
+		events, ok := a.events[id]
+		if !ok {
+			result[id] = models.CalendarBusy{Busy: []models.TimeRange{}, Errors: []string{"notFound"}}
+			continue
+		}
+
+		busy := []models.TimeRange{}
+		for _, ev := range matchEvents(events, query.From, query.To, true) {
+			busy = append(busy, models.TimeRange{Start: ev.StartTime, End: ev.EndTime})
+		}
+		result[id] = models.CalendarBusy{Busy: utils.MergeTimeRanges(busy)}
+	}
+	return result, nil
+}
+
+func (a *MemoryAdapter) SyncEvents(accessToken string, query models.SyncQuery) (models.SyncResult, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	events, ok := a.events[query.CalendarID]
+	if !ok {
+		return models.SyncResult{}, errNotFound()
+	}
+
+	result := models.SyncResult{
+		Events:        []models.Event{},
+		FullSync:      query.SyncToken == "",
+		NextSyncToken: strconv.Itoa(a.seq),
+	}
+
+	if query.SyncToken == "" {
+		for _, e := range events {
+			if e.Status != statusCancelled {
+				result.Events = append(result.Events, utils.ToEvent(e))
+			}
+		}
+		utils.SortEvents(result.Events)
+		return result, nil
+	}
+
+	since, err := strconv.Atoi(query.SyncToken)
+	if err != nil || since < a.syncFloor[query.CalendarID] || since > a.seq {
+		return models.SyncResult{}, &googleapi.Error{Code: http.StatusGone, Message: "Sync token is no longer valid, a full sync is required."}
+	}
+	for id, changed := range a.changes[query.CalendarID] {
+		if changed <= since {
+			continue
+		}
+		if e, ok := events[id]; ok {
+			result.Events = append(result.Events, utils.ToEvent(e))
+		} else if e, ok := a.deleted[query.CalendarID][id]; ok {
+			result.Events = append(result.Events, utils.ToEvent(e))
+		}
+	}
+	utils.SortEvents(result.Events)
+	return result, nil
+}
+
+func (a *MemoryAdapter) GetEvent(accessToken, calendarID, eventID string) (*calendar.Event, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	events := a.events[calendarID]
+	if e, ok := events[eventID]; ok {
+		return cloneEvent(e), nil
+	}
+
+	master, start, ok := lookupInstance(events, eventID)
+	if !ok {
+		return nil, errNotFound()
+	}
+	return instanceOf(master, start), nil
+}
+
+func (a *MemoryAdapter) CreateEvent(accessToken, calendarID string, newEvent *calendar.Event, opts models.WriteOptions) (*calendar.Event, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	events, ok := a.events[calendarID]
+	if !ok {
+		return nil, errNotFound()
+	}
+
+	a.nextID++
+	ev := cloneEvent(newEvent)
+	ev.Id = fmt.Sprintf("mem%d", a.nextID)
+	ev.Status = statusConfirmed
+	ev.Created = time.Now().UTC().Format(time.RFC3339)
+	createConference(ev)
+	events[ev.Id] = ev
+	a.touch(calendarID, ev.Id)
+	return cloneEvent(ev), nil
+}
+
+func (a *MemoryAdapter) UpdateEvent(accessToken, calendarID string, e calendar.Event, opts models.WriteOptions) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	events := a.events[calendarID]
+	existing, ok := events[e.Id]
+	if !ok {
+		master, start, found := lookupInstance(events, e.Id)
+		if !found {
+			return errNotFound()
+		}
+		existing = instanceOf(master, start)
+	}
+	if opts.IfMatch != "" && opts.IfMatch != existing.Etag {
+		return errPreconditionFailed()
+	}
+	e.RecurringEventId = existing.RecurringEventId
+	e.OriginalStartTime = existing.OriginalStartTime
+	if e.ConferenceData == nil {
+		e.ConferenceData = existing.ConferenceData
+		e.HangoutLink = existing.HangoutLink
+	}
+	createConference(&e)
+	if e.Status == "" {
+		e.Status = statusConfirmed
+	}
+	events[e.Id] = cloneEvent(&e)
+	a.touch(calendarID, e.Id)
+	return nil
+}
+
+func (a *MemoryAdapter) PatchEvent(accessToken, calendarID, eventID string, patch *calendar.Event, opts models.WriteOptions) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	events := a.events[calendarID]
+	existing, ok := events[eventID]
+	if !ok {
+		master, start, found := lookupInstance(events, eventID)
+		if !found {
+			return errNotFound()
+		}
+		existing = instanceOf(master, start)
+	}
+	if opts.IfMatch != "" && opts.IfMatch != existing.Etag {
+		return errPreconditionFailed()
+	}
+
+	merged, err := utils.MergeEvent(existing, patch)
+	if err != nil {
+		return err
+	}
+	merged.Id = eventID
+	createConference(merged)
+	events[eventID] = merged
+	a.touch(calendarID, eventID)
+	return nil
+}
+
+// MoveEvent moves an event, or a series with its edited instances, leaving
+// tombstones in the source calendar.
+func (a *MemoryAdapter) MoveEvent(accessToken, calendarID, eventID, destinationID string, opts models.WriteOptions) (*calendar.Event, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	source := a.events[calendarID]
+	existing, ok := source[eventID]
+	if !ok {
+		return nil, errNotFound()
+	}
+	if existing.RecurringEventId != "" {
+		return nil, &googleapi.Error{Code: http.StatusBadRequest, Message: "Cannot change the organizer of an instance."}
+	}
+	destination, ok := a.events[destinationID]
+	if !ok {
+		return nil, errNotFound()
+	}
+
+	for id, e := range source {
+		if id != eventID && e.RecurringEventId != eventID {
+			continue
+		}
+		destination[id] = e
+		a.remove(calendarID, id)
+		a.touch(destinationID, id)
+	}
+	return cloneEvent(destination[eventID]), nil
+}
+
+func (a *MemoryAdapter) DeleteEvent(accessToken, calendarID, eventID string, opts models.WriteOptions) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	events := a.events[calendarID]
+	existing, ok := events[eventID]
+	if !ok {
+		master, start, ok := lookupInstance(events, eventID)
+		if !ok {
+			return errNotFound()
+		}
+		existing = instanceOf(master, start)
+		if opts.IfMatch != "" && opts.IfMatch != existing.Etag {
+			return errPreconditionFailed()
+		}
+		events[eventID] = existing
+	} else if opts.IfMatch != "" && opts.IfMatch != existing.Etag {
+		return errPreconditionFailed()
+	}
+
+	// Deleting an instance cancels it so the series skips that occurrence.
+	if existing.RecurringEventId != "" {
+		existing.Status = statusCancelled
+		a.touch(calendarID, eventID)
+		return nil
+	}
+
+	a.remove(calendarID, eventID)
+	for id, e := range events {
+		if e.RecurringEventId == eventID {
+			a.remove(calendarID, id)
+		}
+	}
+	return nil
+}
+
+// BatchEvents has no round trips to save, so it runs the ops one by one.
+func (a *MemoryAdapter) BatchEvents(accessToken string, ops []EventOp) []EventOpResult {
+	results := make([]EventOpResult, len(ops))
+	for i, op := range ops {
+		switch op.Kind {
+		case BatchInsert:
+			results[i].Event, results[i].Err = a.CreateEvent(accessToken, op.CalendarID, op.Event, op.Opts)
+		case BatchPatch:
+			results[i].Err = a.PatchEvent(accessToken, op.CalendarID, op.EventID, op.Event, op.Opts)
+		case BatchDelete:
+			results[i].Err = a.DeleteEvent(accessToken, op.CalendarID, op.EventID, op.Opts)
+		default:
+			results[i].Err = fmt.Errorf("unknown batch operation %q", op.Kind)
+		}
+	}
+	return results
+}
+
+// createConference answers a pending conference request with a fake Meet link.
+func createConference(ev *calendar.Event) {
+	c := ev.ConferenceData
+	if c == nil || c.CreateRequest == nil || c.ConferenceId != "" {
+		return
+	}
+	c.ConferenceId = c.CreateRequest.RequestId
+	c.CreateRequest.Status = &calendar.ConferenceRequestStatus{StatusCode: "success"}
+	c.ConferenceSolution = &calendar.ConferenceSolution{
+		Key:  c.CreateRequest.ConferenceSolutionKey,
+		Name: "Google Meet",
+	}
+	uri := "https://meet.memory/" + c.ConferenceId
+	c.EntryPoints = []*calendar.EntryPoint{{EntryPointType: "video", Uri: uri, Label: uri}}
+	ev.HangoutLink = uri
+}
+
+// remove deletes an event and leaves a tombstone for SyncEvents.
+func (a *MemoryAdapter) remove(calendarID, eventID string) {
+	delete(a.events[calendarID], eventID)
+	a.deleted[calendarID][eventID] = &calendar.Event{Id: eventID, Status: statusCancelled}
+	a.touch(calendarID, eventID)
+}
+
+// expandInstances returns the instances of master overlapping [from, to),
+// with edited instances replacing the generated ones.
+func expandInstances(events map[string]*calendar.Event, master *calendar.Event, from, to time.Time) ([]models.Event, error) {
+	start := utils.ParseSeriesStart(master.Start)
+	duration := utils.ParseDateTime(master.End).Sub(start)
+
+	limit := to
+	if limit.IsZero() {
+		limit = maxTime(start, from).AddDate(1, 0, 0)
+	}
+	occurrences, err := utils.ExpandRecurrence(master.Recurrence, start, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []models.Event
+	for _, t := range occurrences {
+		if !from.IsZero() && !t.Add(duration).After(from) {
+			continue
+		}
+
+		id := utils.InstanceID(master.Id, t, isAllDay(master))
+		if override, ok := events[id]; ok {
+			if override.Status != statusCancelled && overlaps(utils.ToEvent(override), from, to) {
+				instances = append(instances, utils.ToEvent(override))
+			}
+			continue
+		}
+		instances = append(instances, utils.ToEvent(instanceOf(master, t)))
+	}
+	return instances, nil
+}
+
+// lookupInstance resolves an instance ID to its master and original start.
+func lookupInstance(events map[string]*calendar.Event, eventID string) (*calendar.Event, time.Time, bool) {
+	masterID, start, ok := utils.ParseInstanceID(eventID)
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	master, ok := events[masterID]
+	if !ok || len(master.Recurrence) == 0 {
+		return nil, time.Time{}, false
+	}
+
+	seriesStart := utils.ParseSeriesStart(master.Start)
+	occurrences, err := utils.ExpandRecurrence(master.Recurrence, seriesStart, start.Add(time.Second))
+	if err == nil {
+		found := false
+		for _, t := range occurrences {
+			found = found || t.Equal(start)
+		}
+		if !found {
+			return nil, time.Time{}, false
+		}
+	}
+	return master, start, true
+}
+
+// instanceOf builds the instance of master that starts at start.
+func instanceOf(master *calendar.Event, start time.Time) *calendar.Event {
+	seriesStart := utils.ParseSeriesStart(master.Start)
+	end := start.Add(utils.ParseDateTime(master.End).Sub(seriesStart))
+
+	inst := cloneEvent(master)
+	inst.Id = utils.InstanceID(master.Id, start, isAllDay(master))
+	inst.Recurrence = nil
+	inst.RecurringEventId = master.Id
+	inst.Start = eventDateTime(master.Start, start)
+	inst.End = eventDateTime(master.End, end)
+	inst.OriginalStartTime = eventDateTime(master.Start, start)
+	return inst
+}
+
+// cloneEvent deep-copies an event so callers never share nested values such
+// as attendees with the store.
+func cloneEvent(e *calendar.Event) *calendar.Event {
+	data, err := json.Marshal(e)
+	if err != nil {
+		panic(fmt.Sprintf("memory adapter: cannot copy event: %v", err))
+	}
+	var clone calendar.Event
+	if err := json.Unmarshal(data, &clone); err != nil {
+		panic(fmt.Sprintf("memory adapter: cannot copy event: %v", err))
+	}
+	return &clone
+}
+
+// eventDateTime formats t like template, keeping its all-day form and zone.
+func eventDateTime(template *calendar.EventDateTime, t time.Time) *calendar.EventDateTime {
+	if template.Date != "" {
+		return &calendar.EventDateTime{Date: t.Format("2006-01-02")}
+	}
+	return &calendar.EventDateTime{
+		DateTime: t.Format(time.RFC3339),
+		TimeZone: template.TimeZone,
+	}
+}
+
+func isAllDay(e *calendar.Event) bool {
+	return e.Start != nil && e.Start.Date != ""
+}
+
+// overlaps applies Google's rule: timeMin bounds the end, timeMax the start.
+func overlaps(ev models.Event, from, to time.Time) bool {
+	if !from.IsZero() && !ev.EndTime.After(from) {
+		return false
+	}
+	if !to.IsZero() && !ev.StartTime.Before(to) {
+		return false
+	}
+	return true
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func parseBound(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: %w", value, err)
+	}
+	return t, nil
+}
+
+// errNotFound mirrors the error the Google client returns for missing
+// resources so callers can handle both providers the same way.
+func errNotFound() error {
+	return &googleapi.Error{Code: http.StatusNotFound, Message: "Not Found"}
+}
+
+func errPreconditionFailed() error {
+	return &googleapi.Error{Code: http.StatusPreconditionFailed, Message: "Precondition Failed"}
+}
@@ -0,0 +1,262 @@
+package adapters
+
+import (
+	"backend/constants"
+	"backend/models"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+func timed(summary, start, end string) *calendar.Event {
+	return &calendar.Event{
+		Summary: summary,
+		Start:   &calendar.EventDateTime{DateTime: start},
+		End:     &calendar.EventDateTime{DateTime: end},
+	}
+}
+
+func mustCreate(t *testing.T, a *MemoryAdapter, ev *calendar.Event) *calendar.Event {
+	t.Helper()
+	created, err := a.CreateEvent("token", constants.PrimaryCalendarID, ev, models.WriteOptions{})
+	if err != nil {
+		t.Fatalf("CreateEvent %q: %v", ev.Summary, err)
+	}
+	return created
+}
+
+// listed returns "summary@start" for every event on the page, in order.
+func listed(page models.EventList) []string {
+	got := []string{}
+	for _, ev := range page.Events {
+		got = append(got, ev.Summary+"@"+ev.StartTime.UTC().Format("01-02T15:04"))
+	}
+	return got
+}
+
+func TestMemoryListEventsTimeRange(t *testing.T) {
+	a := NewMemoryAdapter()
+	mustCreate(t, a, timed("standup", "2030-01-07T09:00:00Z", "2030-01-07T10:00:00Z"))
+	mustCreate(t, a, timed("review", "2030-01-07T10:00:00Z", "2030-01-07T11:00:00Z"))
+	mustCreate(t, a, &calendar.Event{
+		Summary: "offsite",
+		Start:   &calendar.EventDateTime{Date: "2030-01-08"},
+		End:     &calendar.EventDateTime{Date: "2030-01-09"},
+	})
+	lunch := timed("lunch", "2030-01-07T12:00:00Z", "2030-01-07T12:30:00Z")
+	lunch.Recurrence = []string{"RRULE:FREQ=DAILY;COUNT=3"}
+	mustCreate(t, a, lunch)
+
+	tests := []struct {
+		name         string
+		from, to     string
+		singleEvents bool
+		want         []string
+	}{
+		{
+			name: "event ending at from is left out",
+			from: "2030-01-07T10:00:00Z", to: "2030-01-07T11:00:00Z",
+			want: []string{"review@01-07T10:00"},
+		},
+		{
+			name: "event starting at to is left out",
+			from: "2030-01-07T09:30:00Z", to: "2030-01-07T10:00:00Z",
+			want: []string{"standup@01-07T09:00"},
+		},
+		{
+			name: "partial overlap on both ends",
+			from: "2030-01-07T09:59:00Z", to: "2030-01-07T10:01:00Z",
+			want: []string{"standup@01-07T09:00", "review@01-07T10:00"},
+		},
+		{
+			name: "instances are expanded in start order",
+			from: "2030-01-07T00:00:00Z", to: "2030-01-08T00:00:00Z", singleEvents: true,
+			want: []string{"standup@01-07T09:00", "review@01-07T10:00", "lunch@01-07T12:00"},
+		},
+		{
+			name: "all-day event covers its date",
+			from: "2030-01-08T11:00:00Z", to: "2030-01-08T13:00:00Z", singleEvents: true,
+			want: []string{"offsite@01-08T00:00", "lunch@01-08T12:00"},
+		},
+		{
+			name: "all-day event ends at midnight",
+			from: "2030-01-09T00:00:00Z", to: "2030-01-10T00:00:00Z", singleEvents: true,
+			want: []string{"lunch@01-09T12:00"},
+		},
+		{
+			name: "series listed once by its master",
+			from: "2030-01-09T00:00:00Z", to: "2030-01-10T00:00:00Z",
+			want: []string{"lunch@01-07T12:00"},
+		},
+		{
+			name: "range after the last instance",
+			from: "2030-01-10T00:00:00Z", to: "2030-01-11T00:00:00Z", singleEvents: true,
+			want: []string{},
+		},
+		{
+			name: "open range",
+			want: []string{"standup@01-07T09:00", "review@01-07T10:00", "lunch@01-07T12:00", "offsite@01-08T00:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := a.ListEvents("token", models.EventQuery{
+				CalendarID:   constants.PrimaryCalendarID,
+				From:         tt.from,
+				To:           tt.to,
+				SingleEvents: tt.singleEvents,
+			})
+			if err != nil {
+				t.Fatalf("ListEvents: %v", err)
+			}
+			if got := listed(page); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryListEventsOrder(t *testing.T) {
+	a := NewMemoryAdapter()
+	// Created out of start order; the last-modified times are set by hand
+	// because the adapter records them to the second.
+	updated := map[string]string{
+		"late":   "2030-01-01T00:00:01Z",
+		"early":  "2030-01-01T00:00:03Z",
+		"middle": "2030-01-01T00:00:02Z",
+	}
+	for _, ev := range []*calendar.Event{
+		timed("late", "2030-01-09T09:00:00Z", "2030-01-09T10:00:00Z"),
+		timed("early", "2030-01-07T09:00:00Z", "2030-01-07T10:00:00Z"),
+		timed("middle", "2030-01-08T09:00:00Z", "2030-01-08T10:00:00Z"),
+	} {
+		created := mustCreate(t, a, ev)
+		a.events[constants.PrimaryCalendarID][created.Id].Updated = updated[ev.Summary]
+	}
+
+	tests := []struct {
+		orderBy string
+		want    []string
+	}{
+		{"", []string{"early@01-07T09:00", "middle@01-08T09:00", "late@01-09T09:00"}},
+		{constants.OrderByStartTime, []string{"early@01-07T09:00", "middle@01-08T09:00", "late@01-09T09:00"}},
+		{constants.OrderByUpdated, []string{"late@01-09T09:00", "middle@01-08T09:00", "early@01-07T09:00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run("order by "+tt.orderBy, func(t *testing.T) {
+			var got []string
+			query := models.EventQuery{CalendarID: constants.PrimaryCalendarID, SingleEvents: true, OrderBy: tt.orderBy, PageSize: 2}
+			for pages := 0; ; pages++ {
+				if pages == 3 {
+					t.Fatalf("more pages than events; token %q", query.PageToken)
+				}
+				page, err := a.ListEvents("token", query)
+				if err != nil {
+					t.Fatalf("ListEvents: %v", err)
+				}
+				got = append(got, listed(page)...)
+				if page.NextPageToken == "" {
+					break
+				}
+				query.PageToken = page.NextPageToken
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Run with -race: writers create, edit and delete events while readers list
+// and sync the same calendar.
+func TestMemoryConcurrentWritesAndLists(t *testing.T) {
+	const writers, eventsPerWriter = 8, 20
+
+	a := NewMemoryAdapter()
+	cal := constants.PrimaryCalendarID
+	initial, err := a.SyncEvents("token", models.SyncQuery{CalendarID: cal})
+	if err != nil {
+		t.Fatalf("SyncEvents: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*eventsPerWriter)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < eventsPerWriter; i++ {
+				start := fmt.Sprintf("2030-01-%02dT%02d:00:00Z", w+1, i%24)
+				end := fmt.Sprintf("2030-01-%02dT%02d:30:00Z", w+1, i%24)
+				ev, err := a.CreateEvent("token", cal, timed(fmt.Sprintf("w%d-%d", w, i), start, end), models.WriteOptions{})
+				if err != nil {
+					errs <- err
+					return
+				}
+				if err := a.PatchEvent("token", cal, ev.Id, &calendar.Event{Location: "Room " + strconv.Itoa(i)}, models.WriteOptions{IfMatch: ev.Etag}); err != nil {
+					errs <- fmt.Errorf("patch %s: %w", ev.Id, err)
+					return
+				}
+				if err := a.DeleteEvent("token", cal, ev.Id, models.WriteOptions{}); err != nil {
+					errs <- fmt.Errorf("delete %s: %w", ev.Id, err)
+					return
+				}
+			}
+		}(w)
+	}
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if _, err := a.ListEvents("token", models.EventQuery{CalendarID: cal, SingleEvents: true, PageSize: 5}); err != nil {
+					errs <- fmt.Errorf("list: %w", err)
+					return
+				}
+				if _, err := a.SyncEvents("token", models.SyncQuery{CalendarID: cal, SyncToken: initial.NextSyncToken}); err != nil {
+					errs <- fmt.Errorf("sync: %w", err)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(done)
+	readers.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	page, err := a.ListEvents("token", models.EventQuery{CalendarID: cal})
+	if err != nil || len(page.Events) != 0 {
+		t.Errorf("ListEvents after deleting everything = %v, %v", listed(page), err)
+	}
+	delta, err := a.SyncEvents("token", models.SyncQuery{CalendarID: cal, SyncToken: initial.NextSyncToken})
+	if err != nil {
+		t.Fatalf("SyncEvents: %v", err)
+	}
+	if len(delta.Events) != writers*eventsPerWriter {
+		t.Errorf("sync reported %d changes, want %d", len(delta.Events), writers*eventsPerWriter)
+	}
+	for _, ev := range delta.Events {
+		if ev.Status != statusCancelled {
+			t.Errorf("event %s has status %q, want it deleted", ev.ID, ev.Status)
+		}
+	}
+}
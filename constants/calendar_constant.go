@@ -0,0 +1,104 @@
+package constants
+
+const (
+	GoogleCalendarProvider = "google"
+	MemoryCalendarProvider = "memory"
+
+	// PrimaryCalendarID is Google's alias for the user's own calendar.
+	PrimaryCalendarID = "primary"
+
+	// DevAccessToken and DevUserEmail identify the single user of the
+	// memory provider, which runs without authentication.
+	DevAccessToken = "dev"
+	DevUserEmail   = "dev@localhost"
+
+	DefaultEventPageSize = 20
+	MaxEventPageSize     = 2500
+	// MaxFetchAllPages bounds how many pages a fetch_all listing may follow.
+	MaxFetchAllPages = 10
+
+	// MaxFreeBusyCalendars is Google's limit on calendars per free/busy query.
+	MaxFreeBusyCalendars = 50
+
+	// MaxMeetingSearchDays bounds the window of a meeting time search.
+	MaxMeetingSearchDays = 62
+
+	// BulkConcurrency caps the provider requests a bulk operation runs at once.
+	BulkConcurrency = 5
+	// MaxBatchRequests is Google's limit on requests per batch HTTP call.
+	MaxBatchRequests = 50
+	GoogleBatchURL   = "https://www.googleapis.com/batch/calendar/v3"
+
+	// DefaultTimeZone is the zone of the memory provider's primary calendar.
+	DefaultTimeZone = "Asia/Jakarta"
+)
+
+const (
+	ACLRoleFreeBusyReader = "freeBusyReader"
+	ACLRoleReader         = "reader"
+	ACLRoleWriter         = "writer"
+	ACLRoleOwner          = "owner"
+
+	ACLScopeUser   = "user"
+	ACLScopeGroup  = "group"
+	ACLScopeDomain = "domain"
+)
+
+const (
+	ConflictPolicyAllow  = "allow"
+	ConflictPolicyWarn   = "warn"
+	ConflictPolicyReject = "reject"
+)
+
+const (
+	ResponseNeedsAction = "needsAction"
+	ResponseAccepted    = "accepted"
+	ResponseDeclined    = "declined"
+	ResponseTentative   = "tentative"
+)
+
+const (
+	EventStatusConfirmed = "confirmed"
+	EventStatusTentative = "tentative"
+	EventStatusCancelled = "cancelled"
+)
+
+const (
+	TransparencyOpaque      = "opaque"
+	TransparencyTransparent = "transparent"
+)
+
+const (
+	EventTypeDefault         = "default"
+	EventTypeBirthday        = "birthday"
+	EventTypeFocusTime       = "focusTime"
+	EventTypeFromGmail       = "fromGmail"
+	EventTypeOutOfOffice     = "outOfOffice"
+	EventTypeWorkingLocation = "workingLocation"
+)
+
+const (
+	OrderByStartTime = "startTime"
+	OrderByUpdated   = "updated"
+)
+
+const ConferenceTypeMeet = "hangoutsMeet"
+
+const (
+	ReminderMethodPopup  = "popup"
+	ReminderMethodEmail  = "email"
+	MaxReminderMinutes   = 40320
+	MaxReminderOverrides = 5
+)
+
+const (
+	SendUpdatesAll          = "all"
+	SendUpdatesExternalOnly = "externalOnly"
+	SendUpdatesNone         = "none"
+)
+
+const (
+	RecurrenceScopeThis      = "this"
+	RecurrenceScopeFollowing = "following"
+	RecurrenceScopeAll       = "all"
+)
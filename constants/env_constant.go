@@ -13,4 +13,5 @@ const (
 	DB_NAME               = "DB_NAME"
 	PORT                  = "PORT"
 	ENCRYPTION_SECRET_KEY = "ENCRYPTION_SECRET_KEY"
+	CALENDAR_PROVIDER     = "CALENDAR_PROVIDER"
 )
@@ -124,11 +124,43 @@ func (ac *AuthController) GoogleCallback(c echo.Context) error {
 			ExpiresAt: expiresAt,
 			JWT:       jwtToken,
 			User: dtos.UserInfo{
-				ID:    strconv.FormatUint(uint64(user.ID), 10),
-				Email: user.Email,
-				Name:  user.Name,
+				ID:       strconv.FormatUint(uint64(user.ID), 10),
+				Email:    user.Email,
+				Name:     user.Name,
+				TimeZone: user.TimeZone,
 			},
 		},
 		Error: nil,
 	})
 }
+
+func (ac *AuthController) UpdateTimeZone(c echo.Context) error {
+	googleID, _ := c.Get("google_id").(string)
+
+	var req dtos.UpdateTimeZoneRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dtos.Response{
+			Data:  nil,
+			Error: err.Error(),
+		})
+	}
+
+	user, err := ac.authService.UpdateTimeZone(googleID, req.TimeZone)
+	if err != nil {
+		errorMsg := fmt.Errorf("Failed to update time zone: %v", err)
+		return c.JSON(http.StatusBadRequest, dtos.Response{
+			Data:  nil,
+			Error: errorMsg.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, dtos.Response{
+		Data: dtos.UserInfo{
+			ID:       strconv.FormatUint(uint64(user.ID), 10),
+			Email:    user.Email,
+			Name:     user.Name,
+			TimeZone: user.TimeZone,
+		},
+		Error: nil,
+	})
+}
@@ -3,8 +3,13 @@ package controllers
 import (
 	"backend/models"
 	"backend/services"
+	"backend/utils"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 )
@@ -17,6 +22,183 @@ func NewCalendarController(svc *services.CalendarService) *CalendarController {
 	return &CalendarController{Svc: svc}
 }
 
+// svc returns the calendar service bound to the signed-in user and their
+// provider.
+func (c *CalendarController) svc(ctx echo.Context) *services.CalendarService {
+	provider, _ := ctx.Get("calendarProvider").(string)
+	email, _ := ctx.Get("userEmail").(string)
+	return c.Svc.ForProvider(provider).ForUser(email, userTimeZone(ctx))
+}
+
+// userTimeZone returns the stored default time zone of the signed-in user.
+func userTimeZone(ctx echo.Context) string {
+	timeZone, _ := ctx.Get("userTimeZone").(string)
+	return timeZone
+}
+
+func (c *CalendarController) ListCalendars(ctx echo.Context) error {
+	accessToken := ctx.Get("googleAccessToken").(string)
+	if accessToken == "" {
+		return ctx.JSON(http.StatusUnauthorized, echo.Map{
+			"error": "Authorization header with Bearer token required",
+		})
+	}
+
+	calendars, err := c.svc(ctx).ListCalendars(accessToken)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, calendars)
+}
+
+// pathParam returns a path parameter with percent-encoding removed. Calendar
+// and ACL rule IDs may contain characters such as '#' that clients have to
+// encode.
+func pathParam(ctx echo.Context, name string) string {
+	value := ctx.Param(name)
+	if unescaped, err := url.PathUnescape(value); err == nil {
+		return unescaped
+	}
+	return value
+}
+
+func (c *CalendarController) CreateCalendar(ctx echo.Context) error {
+	accessToken := ctx.Get("googleAccessToken").(string)
+	if accessToken == "" {
+		return ctx.JSON(http.StatusUnauthorized, echo.Map{
+			"error": "Authorization header with Bearer token required",
+		})
+	}
+
+	var newCalendar models.CreateCalendar
+	if err := ctx.Bind(&newCalendar); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	created, err := c.svc(ctx).CreateCalendar(accessToken, newCalendar)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, created)
+}
+
+func (c *CalendarController) PatchCalendar(ctx echo.Context) error {
+	accessToken := ctx.Get("googleAccessToken").(string)
+	if accessToken == "" {
+		return ctx.JSON(http.StatusUnauthorized, echo.Map{
+			"error": "Authorization header with Bearer token required",
+		})
+	}
+
+	var patch models.EditCalendar
+	if err := ctx.Bind(&patch); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	updated, err := c.svc(ctx).PatchCalendar(accessToken, pathParam(ctx, "id"), patch)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, updated)
+}
+
+func (c *CalendarController) DeleteCalendar(ctx echo.Context) error {
+	accessToken := ctx.Get("googleAccessToken").(string)
+	if accessToken == "" {
+		return ctx.JSON(http.StatusUnauthorized, echo.Map{
+			"error": "Authorization header with Bearer token required",
+		})
+	}
+
+	if err := c.svc(ctx).DeleteCalendar(accessToken, pathParam(ctx, "id")); err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, "Calendar successfully deleted")
+}
+
+func (c *CalendarController) ClearCalendar(ctx echo.Context) error {
+	accessToken := ctx.Get("googleAccessToken").(string)
+	if accessToken == "" {
+		return ctx.JSON(http.StatusUnauthorized, echo.Map{
+			"error": "Authorization header with Bearer token required",
+		})
+	}
+
+	if err := c.svc(ctx).ClearCalendar(accessToken, pathParam(ctx, "id")); err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, "Calendar successfully cleared")
+}
+
+func (c *CalendarController) ListACLRules(ctx echo.Context) error {
+	accessToken := ctx.Get("googleAccessToken").(string)
+	if accessToken == "" {
+		return ctx.JSON(http.StatusUnauthorized, echo.Map{
+			"error": "Authorization header with Bearer token required",
+		})
+	}
+
+	rules, err := c.svc(ctx).ListACLRules(accessToken, pathParam(ctx, "id"))
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, rules)
+}
+
+func (c *CalendarController) CreateACLRule(ctx echo.Context) error {
+	accessToken := ctx.Get("googleAccessToken").(string)
+	if accessToken == "" {
+		return ctx.JSON(http.StatusUnauthorized, echo.Map{
+			"error": "Authorization header with Bearer token required",
+		})
+	}
+
+	var rule models.CreateACLRule
+	if err := ctx.Bind(&rule); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	created, err := c.svc(ctx).CreateACLRule(accessToken, pathParam(ctx, "id"), rule)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, created)
+}
+
+func (c *CalendarController) UpdateACLRule(ctx echo.Context) error {
+	accessToken := ctx.Get("googleAccessToken").(string)
+	if accessToken == "" {
+		return ctx.JSON(http.StatusUnauthorized, echo.Map{
+			"error": "Authorization header with Bearer token required",
+		})
+	}
+
+	var rule models.EditACLRule
+	if err := ctx.Bind(&rule); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	updated, err := c.svc(ctx).UpdateACLRule(accessToken, pathParam(ctx, "id"), pathParam(ctx, "ruleId"), rule)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, updated)
+}
+
+func (c *CalendarController) DeleteACLRule(ctx echo.Context) error {
+	accessToken := ctx.Get("googleAccessToken").(string)
+	if accessToken == "" {
+		return ctx.JSON(http.StatusUnauthorized, echo.Map{
+			"error": "Authorization header with Bearer token required",
+		})
+	}
+
+	if err := c.svc(ctx).DeleteACLRule(accessToken, pathParam(ctx, "id"), pathParam(ctx, "ruleId")); err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, "Access successfully revoked")
+}
+
 func (c *CalendarController) ListEvents(ctx echo.Context) error {
 	var eventParam models.EventQuery
 	// token := utils.GetBearerToken(ctx)
@@ -39,7 +221,11 @@ func (c *CalendarController) ListEvents(ctx echo.Context) error {
 		})
 	}
 
-	events, err := c.Svc.ListEvents(accessToken, eventParam)
+	if eventParam.TimeZone == "" {
+		eventParam.TimeZone = userTimeZone(ctx)
+	}
+
+	events, err := c.svc(ctx).ListEvents(accessToken, eventParam)
 	if err != nil {
 		fmt.Println("\nerror:", err)
 		return ctx.JSON(http.StatusInternalServerError, err.Error())
@@ -47,6 +233,109 @@ func (c *CalendarController) ListEvents(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, events)
 }
 
+func (c *CalendarController) GetEvent(ctx echo.Context) error {
+	accessToken := ctx.Get("googleAccessToken").(string)
+	if accessToken == "" {
+		return ctx.JSON(http.StatusUnauthorized, echo.Map{
+			"error": "Authorization header with Bearer token required",
+		})
+	}
+
+	timeZone := ctx.QueryParam("time_zone")
+	if timeZone == "" {
+		timeZone = userTimeZone(ctx)
+	}
+
+	event, err := c.svc(ctx).GetEvent(accessToken, ctx.QueryParam("calendar_id"), ctx.Param("id"), timeZone)
+	if utils.HasStatusCode(err, http.StatusNotFound) {
+		return ctx.JSON(http.StatusNotFound, "event not found")
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, event)
+}
+
+func (c *CalendarController) SyncEvents(ctx echo.Context) error {
+	var syncParam models.SyncQuery
+
+	accessToken := ctx.Get("googleAccessToken").(string)
+	if accessToken == "" {
+		return ctx.JSON(http.StatusUnauthorized, echo.Map{
+			"error": "Authorization header with Bearer token required",
+		})
+	}
+
+	if err := ctx.Bind(&syncParam); err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid query parameters",
+		})
+	}
+
+	result, err := c.svc(ctx).SyncEvents(accessToken, syncParam)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+func (c *CalendarController) FreeBusy(ctx echo.Context) error {
+	var query models.FreeBusyQuery
+
+	accessToken := ctx.Get("googleAccessToken").(string)
+	if accessToken == "" {
+		return ctx.JSON(http.StatusUnauthorized, echo.Map{
+			"error": "Authorization header with Bearer token required",
+		})
+	}
+
+	if err := ctx.Bind(&query); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	if query.TimeZone == "" {
+		query.TimeZone = userTimeZone(ctx)
+	}
+
+	result, err := c.svc(ctx).FreeBusy(accessToken, query)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+func (c *CalendarController) FindMeetingTimes(ctx echo.Context) error {
+	var req models.FindMeetingTime
+
+	accessToken := ctx.Get("googleAccessToken").(string)
+	if accessToken == "" {
+		return ctx.JSON(http.StatusUnauthorized, echo.Map{
+			"error": "Authorization header with Bearer token required",
+		})
+	}
+
+	if err := ctx.Bind(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	// Only the requester's own zone is known; other attendees must send theirs.
+	userEmail, _ := ctx.Get("userEmail").(string)
+	for i := range req.Attendees {
+		if req.Attendees[i].TimeZone == "" && userEmail != "" && strings.EqualFold(req.Attendees[i].Email, userEmail) {
+			req.Attendees[i].TimeZone = userTimeZone(ctx)
+		}
+	}
+
+	result, err := c.svc(ctx).FindMeetingTimes(accessToken, req)
+	if errors.Is(err, services.ErrAttendeeTimeZone) {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
 func (c *CalendarController) CreateEvent(ctx echo.Context) error {
 	// token := utils.GetBearerToken(ctx)
 	// if token == "" {
@@ -68,11 +357,54 @@ func (c *CalendarController) CreateEvent(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, err.Error())
 	}
 
-	err := c.Svc.Create(accessToken, newEvents)
+	for i := range newEvents {
+		if newEvents[i].TimeZone == "" {
+			newEvents[i].TimeZone = userTimeZone(ctx)
+		}
+	}
+
+	// atomic=true creates all events or none of them.
+	atomic, _ := strconv.ParseBool(ctx.QueryParam("atomic"))
+
+	results, err := c.svc(ctx).Create(accessToken, newEvents, atomic)
+	conflicts := eventConflicts(results)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, err.Error())
+		status := http.StatusMultiStatus
+		if atomic {
+			status = http.StatusUnprocessableEntity
+		}
+		for _, conflict := range conflicts {
+			if conflict.Rejected {
+				status = http.StatusConflict
+			}
+		}
+		return ctx.JSON(status, echo.Map{
+			"error":     err.Error(),
+			"conflicts": conflicts,
+			"results":   results,
+		})
 	}
-	return ctx.JSON(http.StatusOK, "all events successfully created")
+	return ctx.JSON(http.StatusOK, models.EventWriteResult{
+		Message:   "all events successfully created",
+		Conflicts: conflicts,
+		Results:   results,
+	})
+}
+
+// eventConflicts collects the overlaps reported for the items of a bulk write.
+func eventConflicts(results []models.EventResult) []models.EventConflict {
+	var conflicts []models.EventConflict
+	for _, r := range results {
+		if len(r.Conflicts) > 0 {
+			conflicts = append(conflicts, models.EventConflict{
+				Index:     r.Index,
+				Summary:   r.Summary,
+				Rejected:  r.Rejected,
+				Conflicts: r.Conflicts,
+			})
+		}
+	}
+	return conflicts
 }
 
 func (c *CalendarController) UpdateEvent(ctx echo.Context) error {
@@ -92,10 +424,184 @@ func (c *CalendarController) UpdateEvent(ctx echo.Context) error {
 		})
 	}
 
-	if err := c.Svc.Update(accessToken, eventParam); err != nil {
+	if eventParam.TimeZone == "" {
+		eventParam.TimeZone = userTimeZone(ctx)
+	}
+	eventParam.ETag = ctx.Request().Header.Get("If-Match")
+
+	conflicts, err := c.svc(ctx).Update(accessToken, eventParam)
+	var stale *services.StaleEventError
+	if errors.As(err, &stale) {
+		return staleEventResponse(ctx, stale)
+	}
+	if errors.Is(err, services.ErrEventConflict) {
+		return ctx.JSON(http.StatusConflict, echo.Map{
+			"error":     err.Error(),
+			"conflicts": conflicts,
+		})
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	result := models.EventWriteResult{Message: "all events successfully edited"}
+	if len(conflicts) > 0 {
+		result.Conflicts = []models.EventConflict{{
+			Summary:   eventParam.Summary,
+			Conflicts: conflicts,
+		}}
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+func (c *CalendarController) PatchEvent(ctx echo.Context) error {
+	var patch models.PatchEvent
+
+	accessToken := ctx.Get("googleAccessToken").(string)
+	if accessToken == "" {
+		return ctx.JSON(http.StatusUnauthorized, echo.Map{
+			"error": "Authorization header with Bearer token required",
+		})
+	}
+
+	if err := ctx.Bind(&patch); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	patch.ETag = ctx.Request().Header.Get("If-Match")
+
+	conflicts, err := c.svc(ctx).Patch(accessToken, ctx.Param("id"), patch)
+	var stale *services.StaleEventError
+	if errors.As(err, &stale) {
+		return staleEventResponse(ctx, stale)
+	}
+	if errors.Is(err, services.ErrEventConflict) {
+		return ctx.JSON(http.StatusConflict, echo.Map{
+			"error":     err.Error(),
+			"conflicts": conflicts,
+		})
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	result := models.EventWriteResult{Message: "event successfully updated"}
+	if len(conflicts) > 0 {
+		conflict := models.EventConflict{Conflicts: conflicts}
+		if patch.Summary != nil {
+			conflict.Summary = *patch.Summary
+		}
+		result.Conflicts = []models.EventConflict{conflict}
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+func (c *CalendarController) RespondToEvent(ctx echo.Context) error {
+	var rsvp models.RSVP
+
+	accessToken := ctx.Get("googleAccessToken").(string)
+	if accessToken == "" {
+		return ctx.JSON(http.StatusUnauthorized, echo.Map{
+			"error": "Authorization header with Bearer token required",
+		})
+	}
+
+	if err := ctx.Bind(&rsvp); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	userEmail, _ := ctx.Get("userEmail").(string)
+	err := c.svc(ctx).Respond(accessToken, userEmail, ctx.Param("id"), rsvp)
+	var stale *services.StaleEventError
+	if errors.As(err, &stale) {
+		return staleEventResponse(ctx, stale)
+	}
+	if errors.Is(err, services.ErrNotInvited) {
+		return ctx.JSON(http.StatusForbidden, err.Error())
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, "Response successfully saved")
+}
+
+func (c *CalendarController) MoveEvent(ctx echo.Context) error {
+	var move models.MoveEvent
+
+	accessToken := ctx.Get("googleAccessToken").(string)
+	if accessToken == "" {
+		return ctx.JSON(http.StatusUnauthorized, echo.Map{
+			"error": "Authorization header with Bearer token required",
+		})
+	}
+
+	if err := ctx.Bind(&move); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	event, err := c.svc(ctx).Move(accessToken, ctx.Param("id"), move)
+	if errors.Is(err, services.ErrMoveInstance) {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+	if utils.HasStatusCode(err, http.StatusNotFound) {
+		return ctx.JSON(http.StatusNotFound, "event or calendar not found")
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, event)
+}
+
+func (c *CalendarController) BulkDeleteEvents(ctx echo.Context) error {
+	var bulk models.BulkDelete
+
+	accessToken := ctx.Get("googleAccessToken").(string)
+	if accessToken == "" {
+		return ctx.JSON(http.StatusUnauthorized, echo.Map{
+			"error": "Authorization header with Bearer token required",
+		})
+	}
+
+	if err := ctx.Bind(&bulk); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	result, err := c.svc(ctx).BulkDelete(accessToken, bulk)
+	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, err.Error())
 	}
-	return ctx.JSON(http.StatusOK, "all events successfully edited")
+	return ctx.JSON(bulkStatus(result), result)
+}
+
+func (c *CalendarController) BulkPatchEvents(ctx echo.Context) error {
+	var bulk models.BulkPatch
+
+	accessToken := ctx.Get("googleAccessToken").(string)
+	if accessToken == "" {
+		return ctx.JSON(http.StatusUnauthorized, echo.Map{
+			"error": "Authorization header with Bearer token required",
+		})
+	}
+
+	if err := ctx.Bind(&bulk); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	result, err := c.svc(ctx).BulkPatch(accessToken, bulk)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return ctx.JSON(bulkStatus(result), result)
+}
+
+// bulkStatus is 207 Multi-Status when some items of a bulk operation failed.
+func bulkStatus(result models.BulkResult) int {
+	for _, r := range result.Results {
+		if r.Error != "" {
+			return http.StatusMultiStatus
+		}
+	}
+	return http.StatusOK
 }
 
 func (c *CalendarController) DeleteEvent(ctx echo.Context) error {
@@ -111,9 +617,30 @@ func (c *CalendarController) DeleteEvent(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, "accessToken and event id required")
 	}
 
+	d := models.DeleteEvent{
+		CalendarID:  ctx.QueryParam("calendar_id"),
+		Scope:       ctx.QueryParam("scope"),
+		SendUpdates: ctx.QueryParam("send_updates"),
+		ETag:        ctx.Request().Header.Get("If-Match"),
+	}
+
 	fmt.Println("eventId: ", eventID)
-	if err := c.Svc.Delete(accessToken, eventID); err != nil {
+	err := c.svc(ctx).Delete(accessToken, eventID, d)
+	var stale *services.StaleEventError
+	if errors.As(err, &stale) {
+		return staleEventResponse(ctx, stale)
+	}
+	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, err.Error())
 	}
 	return ctx.JSON(http.StatusOK, "Event successfully deleted")
 }
+
+// staleEventResponse answers a failed If-Match with the current event so the
+// client can merge its changes.
+func staleEventResponse(ctx echo.Context, stale *services.StaleEventError) error {
+	return ctx.JSON(http.StatusPreconditionFailed, echo.Map{
+		"error": stale.Error(),
+		"event": stale.Current,
+	})
+}
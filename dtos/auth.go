@@ -20,9 +20,14 @@ type AuthResponse struct {
 }
 
 type UserInfo struct {
-	ID    string `json:"id"`
-	Email string `json:"email"`
-	Name  string `json:"name"`
+	ID       string `json:"id"`
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	TimeZone string `json:"time_zone,omitempty"`
+}
+
+type UpdateTimeZoneRequest struct {
+	TimeZone string `json:"time_zone"`
 }
 
 type Claims struct {
@@ -27,6 +27,35 @@ func main() {
 		log.Println("No .env file found or failed to load")
 	}
 
+	e := echo.New()
+
+	// middleware
+	e.Use(middleware.Logger())
+	e.Use(middleware.Recover())
+	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins:     []string{"http://localhost:5173"},
+		AllowMethods:     []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete},
+		AllowCredentials: true,
+	}))
+
+	if os.Getenv(constants.CALENDAR_PROVIDER) == constants.MemoryCalendarProvider {
+		setupMemoryServer(e)
+	} else {
+		setupGoogleServer(e)
+	}
+
+	port := os.Getenv(constants.PORT)
+	if port == "" {
+		port = "8080"
+	}
+
+	log.Printf("Server starting on port %s", port)
+	log.Fatal(e.Start(":" + port))
+}
+
+// setupGoogleServer serves the Google Calendar backed API with database
+// users and Google sign-in.
+func setupGoogleServer(e *echo.Echo) {
 	dbUser := os.Getenv(constants.DB_USER)
 	dbPass := os.Getenv(constants.DB_PASSWORD)
 	dbHost := os.Getenv(constants.DB_HOST)
@@ -41,31 +70,25 @@ func main() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	if err := repositories.Migrate(db); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
 	// repositories
 	userRepo := repositories.NewUserRepository(db)
 
-	// adapters
-	calendarAdapter := adapters.NewGoogleAdapter()
-
 	// services
 	authService := services.NewAuthService(userRepo)
-	calendarService := services.NewCalendarService(calendarAdapter)
+	googleAdapter := adapters.NewGoogleAdapter()
+	calendarService := services.NewCalendarService(googleAdapter)
+	// Requests use the provider stored on the user; empty or unknown values
+	// fall back to Google.
+	calendarService.RegisterProvider(constants.GoogleCalendarProvider, googleAdapter)
 
 	// controllers
 	authController := controllers.NewAuthController(authService)
 	CalendarController := controllers.NewCalendarController(calendarService)
 
-	e := echo.New()
-
-	// middleware
-	e.Use(middleware.Logger())
-	e.Use(middleware.Recover())
-	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins:     []string{"http://localhost:5173"},
-		AllowMethods:     []string{http.MethodGet, http.MethodPost},
-		AllowCredentials: true,
-	}))
-
 	routes.SetupAuthRoutes(e, authController)
 
 	// routes
@@ -74,12 +97,19 @@ func main() {
 		middlewares.TokenRefreshMiddleware(authController.GoogleOAuthConfig, userRepo),
 	)
 	routes.SetupCalenderRoutes(calendarGroup, CalendarController)
+}
 
-	port := os.Getenv(constants.PORT)
-	if port == "" {
-		port = "8080"
-	}
+// setupMemoryServer serves the calendar API from an in-memory store for
+// offline development. There is no database, sign-in or token refresh, and
+// all requests share one store.
+func setupMemoryServer(e *echo.Echo) {
+	log.Println("Using the in-memory calendar provider; authentication is disabled")
 
-	log.Printf("Server starting on port %s", port)
-	log.Fatal(e.Start(":" + port))
+	memoryAdapter := adapters.NewMemoryAdapter()
+	calendarService := services.NewCalendarService(memoryAdapter)
+	calendarService.RegisterProvider(constants.MemoryCalendarProvider, memoryAdapter)
+	CalendarController := controllers.NewCalendarController(calendarService)
+
+	calendarGroup := e.Group("/calendar", middlewares.DevSessionMiddleware())
+	routes.SetupCalenderRoutes(calendarGroup, CalendarController)
 }
@@ -0,0 +1,23 @@
+package middlewares
+
+import (
+	"backend/constants"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DevSessionMiddleware replaces JWTMiddleware and TokenRefreshMiddleware when
+// the memory provider is selected, so the calendar API runs without MySQL or
+// Google credentials. Every request acts as the same development user; it
+// must never be used with the Google provider.
+func DevSessionMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("googleAccessToken", constants.DevAccessToken)
+			c.Set("calendarProvider", constants.MemoryCalendarProvider)
+			c.Set("userTimeZone", "")
+			c.Set("userEmail", constants.DevUserEmail)
+			return next(c)
+		}
+	}
+}
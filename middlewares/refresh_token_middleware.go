@@ -52,6 +52,9 @@ func TokenRefreshMiddleware(
 			}
 
 			c.Set("googleAccessToken", accessToken)
+			c.Set("calendarProvider", u.CalendarProvider)
+			c.Set("userTimeZone", u.TimeZone)
+			c.Set("userEmail", u.Email)
 
 			return next(c)
 		}
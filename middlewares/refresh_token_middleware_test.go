@@ -0,0 +1,102 @@
+package middlewares
+
+import (
+	"backend/adapters"
+	"backend/constants"
+	"backend/controllers"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/oauth2"
+)
+
+type fakeUserRepository struct {
+	users map[string]*models.User
+}
+
+func (r *fakeUserRepository) GetByGoogleID(googleID string) (*models.User, error) {
+	u, ok := r.users[googleID]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return u, nil
+}
+
+func (r *fakeUserRepository) GetByEmail(email string) (*models.User, error) {
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeUserRepository) Create(user *models.User) error { return nil }
+func (r *fakeUserRepository) Update(user *models.User) error { return nil }
+
+// The provider stored on the user record decides which backend serves the
+// user's calendar requests.
+func TestTokenRefreshMiddlewareSelectsUserProvider(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	t.Setenv(constants.ENCRYPTION_SECRET_KEY, base64.StdEncoding.EncodeToString(key))
+	token, err := utils.EncryptAccessToken("access", key)
+	if err != nil {
+		t.Fatalf("EncryptAccessToken: %v", err)
+	}
+
+	// Two stores stand in for two providers; only the second one has the
+	// "Team" calendar.
+	defaultProvider := adapters.NewMemoryAdapter()
+	userProvider := adapters.NewMemoryAdapter()
+	if _, err := userProvider.CreateCalendar("access", models.CreateCalendar{Summary: "Team"}); err != nil {
+		t.Fatalf("CreateCalendar: %v", err)
+	}
+	svc := services.NewCalendarService(defaultProvider)
+	svc.RegisterProvider(constants.GoogleCalendarProvider, defaultProvider)
+	svc.RegisterProvider(constants.MemoryCalendarProvider, userProvider)
+
+	expiry := time.Now().Add(time.Hour)
+	repo := &fakeUserRepository{users: map[string]*models.User{
+		"g-memory":  {GoogleID: "g-memory", AccessToken: token, Expiry: expiry, CalendarProvider: constants.MemoryCalendarProvider},
+		"g-google":  {GoogleID: "g-google", AccessToken: token, Expiry: expiry, CalendarProvider: constants.GoogleCalendarProvider},
+		"g-default": {GoogleID: "g-default", AccessToken: token, Expiry: expiry},
+	}}
+
+	e := echo.New()
+	controller := controllers.NewCalendarController(svc)
+	refresh := TokenRefreshMiddleware(&oauth2.Config{}, repo)
+
+	tests := []struct {
+		googleID string
+		want     int
+	}{
+		{"g-memory", 2},
+		{"g-google", 1},
+		{"g-default", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.googleID, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(httptest.NewRequest(http.MethodGet, "/calendar/calendars", nil), rec)
+			ctx.Set("google_id", tt.googleID)
+
+			if err := refresh(controller.ListCalendars)(ctx); err != nil {
+				t.Fatalf("handler: %v", err)
+			}
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+			}
+			var calendars []models.Calendar
+			if err := json.Unmarshal(rec.Body.Bytes(), &calendars); err != nil {
+				t.Fatalf("decode %s: %v", rec.Body, err)
+			}
+			if len(calendars) != tt.want {
+				t.Errorf("got %d calendars, want %d: %+v", len(calendars), tt.want, calendars)
+			}
+		})
+	}
+}
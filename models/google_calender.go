@@ -4,38 +4,395 @@ import "time"
 
 type Event struct {
 	ID          string    `json:"id,omitempty"`
+	ETag        string    `json:"etag,omitempty"`
 	Summary     string    `json:"summary"`
 	Description string    `json:"description"`
 	Location    string    `json:"location"`
 	StartTime   time.Time `json:"start_time"`
 	EndTime     time.Time `json:"end_time"`
+	// AllDay events also carry their dates; EndDate is exclusive.
+	AllDay    bool        `json:"all_day"`
+	StartDate string      `json:"start_date,omitempty"`
+	EndDate   string      `json:"end_date,omitempty"`
+	Status    string      `json:"status,omitempty"`
+	EventType string      `json:"event_type,omitempty"`
+	Attendees []Attendees `json:"attendees,omitempty"`
+	// Transparency is "transparent" for events that do not block time.
+	Transparency string `json:"transparency,omitempty"`
+	// Recurrence is only set on series masters.
+	Recurrence []string `json:"recurrence,omitempty"`
+	// RecurringEventID points an expanded instance at its series master.
+	RecurringEventID  string      `json:"recurring_event_id,omitempty"`
+	OriginalStartTime *time.Time  `json:"original_start_time,omitempty"`
+	Reminders         *Reminders  `json:"reminders,omitempty"`
+	Conference        *Conference `json:"conference,omitempty"`
+	HTMLLink          string      `json:"html_link,omitempty"`
+	Creator           *Person     `json:"creator,omitempty"`
+	Organizer         *Person     `json:"organizer,omitempty"`
+	Created           *time.Time  `json:"created,omitempty"`
+	Updated           *time.Time  `json:"updated,omitempty"`
+}
+
+type Person struct {
+	Email       string `json:"email,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+	Self        bool   `json:"self,omitempty"`
+}
+
+type Conference struct {
+	ID string `json:"id,omitempty"`
+	// Status is "pending" while Google is still creating the conference.
+	Status      string                 `json:"status,omitempty"`
+	JoinURL     string                 `json:"join_url,omitempty"`
+	EntryPoints []ConferenceEntryPoint `json:"entry_points,omitempty"`
+}
+
+type ConferenceEntryPoint struct {
+	// Type is "video", "phone", "sip" or "more".
+	Type  string `json:"type"`
+	URI   string `json:"uri"`
+	Label string `json:"label,omitempty"`
+	PIN   string `json:"pin,omitempty"`
+}
+
+// Reminders either uses the calendar's default reminders or lists its own.
+type Reminders struct {
+	UseDefault bool       `json:"use_default"`
+	Overrides  []Reminder `json:"overrides,omitempty"`
+}
+
+type Reminder struct {
+	// Method is "popup" or "email".
+	Method string `json:"method"`
+	// Minutes before the start of the event, from 0 to 40320 (four weeks).
+	Minutes int64 `json:"minutes"`
+}
+
+type Calendar struct {
+	ID          string `json:"id"`
+	Summary     string `json:"summary"`
+	Description string `json:"description,omitempty"`
+	Color       string `json:"color,omitempty"`
+	AccessRole  string `json:"access_role"`
+	TimeZone    string `json:"time_zone,omitempty"`
+	Primary     bool   `json:"primary"`
+}
+
+type CreateCalendar struct {
+	Summary     string `json:"summary"`
+	Description string `json:"description,omitempty"`
+	TimeZone    string `json:"time_zone,omitempty"`
+}
+
+// EditCalendar only changes the fields that are set.
+type EditCalendar struct {
+	Summary     *string `json:"summary,omitempty"`
+	Description *string `json:"description,omitempty"`
+	TimeZone    *string `json:"time_zone,omitempty"`
+}
+
+type ACLRule struct {
+	ID         string `json:"id"`
+	Role       string `json:"role"`
+	ScopeType  string `json:"scope_type"`
+	ScopeValue string `json:"scope_value,omitempty"`
+}
+
+type CreateACLRule struct {
+	Role       string `json:"role"`
+	ScopeType  string `json:"scope_type"`
+	ScopeValue string `json:"scope_value"`
+	// SendNotifications emails the grantee about the new access.
+	SendNotifications bool `json:"send_notifications,omitempty"`
+}
+
+type EditACLRule struct {
+	Role string `json:"role"`
 }
 
 type EventQuery struct {
-	CalendarID string `json:"calendar_id"`
-	From       string `json:"from,omitempty"`
-	To         string `json:"to,omitempty"`
+	CalendarID string `json:"calendar_id" query:"calendar_id"`
+	From       string `json:"from,omitempty" query:"from"`
+	To         string `json:"to,omitempty" query:"to"`
+	// SingleEvents expands recurring series into their instances instead of
+	// returning the series masters.
+	SingleEvents bool `json:"single_events,omitempty" query:"single_events"`
+	// TimeZone is the IANA zone the returned times are rendered in.
+	TimeZone  string `json:"time_zone,omitempty" query:"time_zone"`
+	PageSize  int    `json:"page_size,omitempty" query:"page_size"`
+	PageToken string `json:"page_token,omitempty" query:"page_token"`
+	// FetchAll follows next page tokens up to constants.MaxFetchAllPages.
+	FetchAll bool `json:"fetch_all,omitempty" query:"fetch_all"`
+
+	// Q is free text matched against summary, description, location and
+	// attendees.
+	Q             string `json:"q,omitempty" query:"q"`
+	AttendeeEmail string `json:"attendee_email,omitempty" query:"attendee_email"`
+	// Location matches events whose location contains it.
+	Location string `json:"location,omitempty" query:"location"`
+	// Status is "confirmed", "tentative" or "cancelled".
+	Status    string `json:"status,omitempty" query:"status"`
+	EventType string `json:"event_type,omitempty" query:"event_type"`
+	// UpdatedMin is an RFC3339 time; older events are left out.
+	UpdatedMin string `json:"updated_min,omitempty" query:"updated_min"`
+	// OrderBy is "startTime" (requires single_events) or "updated".
+	OrderBy string `json:"order_by,omitempty" query:"order_by"`
+}
+
+type EventList struct {
+	Events []Event `json:"events"`
+	// NextPageToken is empty on the last page.
+	NextPageToken string `json:"next_page_token,omitempty"`
+}
+
+type SyncQuery struct {
+	CalendarID string `json:"calendar_id" query:"calendar_id"`
+	// SyncToken is the next_sync_token of a previous sync; empty for a full sync.
+	SyncToken string `json:"sync_token,omitempty" query:"sync_token"`
+	PageToken string `json:"page_token,omitempty" query:"page_token"`
+}
+
+type SyncResult struct {
+	// Events holds changed events; deleted ones have status "cancelled".
+	Events []Event `json:"events"`
+	// FullSync is set when Events is the complete calendar rather than a
+	// delta, including when an expired sync token forced a resync.
+	FullSync      bool   `json:"full_sync"`
+	NextPageToken string `json:"next_page_token,omitempty"`
+	NextSyncToken string `json:"next_sync_token,omitempty"`
+}
+
+type TimeRange struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type FreeBusyQuery struct {
+	// Calendars holds calendar IDs; a user's email is their primary calendar.
+	Calendars []string  `json:"calendars"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	TimeZone  string    `json:"time_zone,omitempty"`
+}
+
+type CalendarBusy struct {
+	Busy   []TimeRange `json:"busy"`
+	Errors []string    `json:"errors,omitempty"`
+}
+
+type FreeBusyResult struct {
+	Calendars map[string]CalendarBusy `json:"calendars"`
+	// Busy merges the busy time of every calendar; Free holds the gaps
+	// between those intervals within the queried window.
+	Busy []TimeRange `json:"busy"`
+	Free []TimeRange `json:"free"`
+}
+
+type WorkingHours struct {
+	Start string `json:"start"` // "09:00"
+	End   string `json:"end"`   // "17:00"
+	// Days are weekday names such as "monday"; empty means Monday to Friday.
+	Days []string `json:"days,omitempty"`
+}
+
+type MeetingAttendee struct {
+	Email        string        `json:"email"`
+	Optional     bool          `json:"optional,omitempty"`
+	TimeZone     string        `json:"time_zone,omitempty"`
+	WorkingHours *WorkingHours `json:"working_hours,omitempty"`
+}
+
+type FindMeetingTime struct {
+	Attendees       []MeetingAttendee `json:"attendees"`
+	DurationMinutes int               `json:"duration_minutes"`
+	From            time.Time         `json:"from"`
+	To              time.Time         `json:"to"`
+	BufferMinutes   int               `json:"buffer_minutes,omitempty"`
+	StepMinutes     int               `json:"step_minutes,omitempty"`
+	MaxResults      int               `json:"max_results,omitempty"`
+}
+
+type MeetingSlot struct {
+	Start             time.Time `json:"start"`
+	End               time.Time `json:"end"`
+	OptionalConflicts []string  `json:"optional_conflicts"`
+	BufferConflicts   int       `json:"buffer_conflicts"`
+}
+
+type MeetingTimes struct {
+	Slots []MeetingSlot `json:"slots"`
+	// Errors lists attendees whose availability could not be read, e.g.
+	// because their calendar is not shared.
+	Errors map[string][]string `json:"errors,omitempty"`
 }
 
 type CreateEvent struct {
+	CalendarID  string      `json:"calendar_id,omitempty"`
 	Summary     string      `json:"summary"`
 	Description string      `json:"description,omitempty"`
 	Location    string      `json:"location,omitempty"`
 	StartTime   time.Time   `json:"start_time"`
 	EndTime     time.Time   `json:"end_time"`
+	AllDay      bool        `json:"all_day,omitempty"`
+	TimeZone    string      `json:"time_zone,omitempty"`
 	Attendees   []Attendees `json:"attendees,omitempty"`
+	Recurrence  []string    `json:"recurrence,omitempty"`
+	// Reminders nil keeps the calendar's default reminders.
+	Reminders *Reminders `json:"reminders,omitempty"`
+	// CreateConference adds a Google Meet link to the event.
+	CreateConference bool `json:"create_conference,omitempty"`
+	// ConflictPolicy is "allow" (default), "warn" or "reject" for overlaps
+	// with existing events in the target calendar.
+	ConflictPolicy string `json:"conflict_policy,omitempty"`
+	// SendUpdates is "all", "externalOnly" or "none" and controls which
+	// attendees are emailed.
+	SendUpdates string `json:"send_updates,omitempty"`
 }
 
 type EditEvent struct {
 	ID          string      `json:"id,omitempty"`
+	CalendarID  string      `json:"calendar_id,omitempty"`
 	Summary     string      `json:"summary,omitempty"`
 	Description string      `json:"description,omitempty"`
 	Location    string      `json:"location,omitempty"`
 	StartTime   time.Time   `json:"start_time,omitempty"`
 	EndTime     time.Time   `json:"end_time,omitempty"`
+	AllDay      bool        `json:"all_day,omitempty"`
+	TimeZone    string      `json:"time_zone,omitempty"`
 	Attendees   []Attendees `json:"attendees,omitempty"`
+	Recurrence  []string    `json:"recurrence,omitempty"`
+	Reminders   *Reminders  `json:"reminders,omitempty"`
+	// CreateConference adds a Google Meet link; existing conferences are kept
+	// otherwise.
+	CreateConference bool `json:"create_conference,omitempty"`
+	// Scope picks which part of a recurring series an edit applies to:
+	// "this" (default), "following" or "all".
+	Scope          string `json:"scope,omitempty"`
+	ConflictPolicy string `json:"conflict_policy,omitempty"`
+	SendUpdates    string `json:"send_updates,omitempty"`
+	// ETag comes from the If-Match header.
+	ETag string `json:"-"`
+}
+
+// PatchEvent changes only the fields that are present; nil fields keep their
+// current value.
+type PatchEvent struct {
+	CalendarID  string       `json:"calendar_id,omitempty"`
+	Summary     *string      `json:"summary,omitempty"`
+	Description *string      `json:"description,omitempty"`
+	Location    *string      `json:"location,omitempty"`
+	StartTime   *time.Time   `json:"start_time,omitempty"`
+	EndTime     *time.Time   `json:"end_time,omitempty"`
+	AllDay      *bool        `json:"all_day,omitempty"`
+	TimeZone    *string      `json:"time_zone,omitempty"`
+	Attendees   *[]Attendees `json:"attendees,omitempty"`
+	Recurrence  *[]string    `json:"recurrence,omitempty"`
+	Reminders   *Reminders   `json:"reminders,omitempty"`
+
+	CreateConference bool   `json:"create_conference,omitempty"`
+	Scope            string `json:"scope,omitempty"`
+	ConflictPolicy   string `json:"conflict_policy,omitempty"`
+	SendUpdates      string `json:"send_updates,omitempty"`
+	// ETag comes from the If-Match header.
+	ETag string `json:"-"`
+}
+
+type MoveEvent struct {
+	// SourceCalendarID defaults to the primary calendar.
+	SourceCalendarID      string `json:"source_calendar_id,omitempty"`
+	DestinationCalendarID string `json:"destination_calendar_id"`
+	SendUpdates           string `json:"send_updates,omitempty"`
+	// Scope must be "all" to move the series an instance belongs to.
+	Scope string `json:"scope,omitempty"`
+}
+
+type DeleteEvent struct {
+	CalendarID  string
+	Scope       string
+	SendUpdates string
+	ETag        string
+}
+
+// WriteOptions are passed to providers along with event writes.
+type WriteOptions struct {
+	SendUpdates string
+	// IfMatch makes the write fail with HTTP 412 unless the stored event
+	// still has this ETag.
+	IfMatch string
+}
+
+// EventConflict lists the existing events a new or edited event overlaps.
+type EventConflict struct {
+	Index     int     `json:"index"`
+	Summary   string  `json:"summary"`
+	Rejected  bool    `json:"rejected"`
+	Conflicts []Event `json:"conflicts"`
+}
+
+type EventWriteResult struct {
+	Message   string          `json:"message"`
+	Conflicts []EventConflict `json:"conflicts,omitempty"`
+	Results   []EventResult   `json:"results,omitempty"`
+}
+
+// BulkSelection picks the events of a bulk operation, either by ID or by a
+// time range with optional search text. Recurring events matched by the
+// query are handled per instance.
+type BulkSelection struct {
+	CalendarID string   `json:"calendar_id,omitempty"`
+	IDs        []string `json:"ids,omitempty"`
+	From       string   `json:"from,omitempty"`
+	To         string   `json:"to,omitempty"`
+	Q          string   `json:"q,omitempty"`
+	// DryRun lists the selected events without changing them.
+	DryRun      bool   `json:"dry_run,omitempty"`
+	SendUpdates string `json:"send_updates,omitempty"`
+}
+
+type BulkDelete struct {
+	BulkSelection
+}
+
+type BulkPatch struct {
+	BulkSelection
+	// ShiftMinutes moves each event, keeping its duration; it may be negative.
+	// All-day events are only moved by multiples of 1440.
+	ShiftMinutes int     `json:"shift_minutes,omitempty"`
+	Location     *string `json:"location,omitempty"`
+}
+
+type BulkResult struct {
+	DryRun  bool          `json:"dry_run,omitempty"`
+	Results []EventResult `json:"results"`
+}
+
+// EventResult is the outcome of one item of a bulk operation.
+type EventResult struct {
+	Index   int    `json:"index"`
+	Summary string `json:"summary,omitempty"`
+	EventID string `json:"event_id,omitempty"`
+	Error   string `json:"error,omitempty"`
+	// Rejected is set when the conflict policy "reject" stopped the item.
+	Rejected  bool    `json:"rejected,omitempty"`
+	Conflicts []Event `json:"conflicts,omitempty"`
+	// RolledBack marks events that were created and then deleted again
+	// because another item of an all-or-nothing batch failed.
+	RolledBack bool `json:"rolled_back,omitempty"`
 }
 
 type Attendees struct {
-	Email string `json:"email"`
+	Email       string `json:"email"`
+	DisplayName string `json:"display_name,omitempty"`
+	Optional    bool   `json:"optional,omitempty"`
+	// ResponseStatus is "needsAction", "accepted", "declined" or "tentative".
+	ResponseStatus string `json:"response_status,omitempty"`
+	// Organizer and Self are set by the calendar and ignored on writes.
+	Organizer bool `json:"organizer,omitempty"`
+	Self      bool `json:"self,omitempty"`
+}
+
+type RSVP struct {
+	CalendarID string `json:"calendar_id,omitempty"`
+	Response   string `json:"response"`
+	Comment    string `json:"comment,omitempty"`
 }
@@ -7,15 +7,17 @@ import (
 )
 
 type User struct {
-	ID           uint           `json:"id" gorm:"primarykey"`
-	GoogleID     string         `json:"google_id" gorm:"uniqueIndex;not null"`
-	FolderID     string         `json:"folder_id"`
-	Email        string         `json:"email" gorm:"uniqueIndex;not null"`
-	RefreshToken string         `json:"refresh_token" gorm:"uniqueIndex;not null"`
-	AccessToken  string         `json:"access_token" gorm:"uniqueIndex;not null"`
-	Expiry       time.Time      `json:"expiry" gorm:"not null"`
-	Name         string         `json:"name"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+	ID               uint           `json:"id" gorm:"primarykey"`
+	GoogleID         string         `json:"google_id" gorm:"uniqueIndex;not null"`
+	FolderID         string         `json:"folder_id"`
+	Email            string         `json:"email" gorm:"uniqueIndex;not null"`
+	RefreshToken     string         `json:"refresh_token" gorm:"uniqueIndex;not null"`
+	AccessToken      string         `json:"access_token" gorm:"uniqueIndex;not null"`
+	Expiry           time.Time      `json:"expiry" gorm:"not null"`
+	Name             string         `json:"name"`
+	CalendarProvider string         `json:"calendar_provider" gorm:"size:32"`
+	TimeZone         string         `json:"time_zone" gorm:"size:64"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
 }
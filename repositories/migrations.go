@@ -0,0 +1,28 @@
+package repositories
+
+import (
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+// userColumns are the users columns added after the table was first created.
+// The table itself is still managed outside the app.
+var userColumns = []string{
+	"CalendarProvider",
+	"TimeZone",
+}
+
+// Migrate adds the missing userColumns to an existing users table.
+func Migrate(db *gorm.DB) error {
+	migrator := db.Migrator()
+	for _, column := range userColumns {
+		if migrator.HasColumn(&models.User{}, column) {
+			continue
+		}
+		if err := migrator.AddColumn(&models.User{}, column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
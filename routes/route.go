@@ -2,6 +2,7 @@ package routes
 
 import (
 	"backend/controllers"
+	"backend/middlewares"
 
 	"github.com/labstack/echo/v4"
 )
@@ -10,11 +11,30 @@ func SetupAuthRoutes(e *echo.Echo, authController *controllers.AuthController) {
 	auth := e.Group("/auth")
 
 	auth.GET("/google/callback", authController.GoogleCallback)
+	auth.POST("/time-zone", authController.UpdateTimeZone, middlewares.JWTMiddleware())
 }
 
 func SetupCalenderRoutes(g *echo.Group, calenderController *controllers.CalendarController) {
+	g.GET("/calendars", calenderController.ListCalendars)
+	g.POST("/calendars", calenderController.CreateCalendar)
+	g.PATCH("/calendars/:id", calenderController.PatchCalendar)
+	g.DELETE("/calendars/:id", calenderController.DeleteCalendar)
+	g.POST("/calendars/:id/clear", calenderController.ClearCalendar)
+	g.GET("/calendars/:id/acl", calenderController.ListACLRules)
+	g.POST("/calendars/:id/acl", calenderController.CreateACLRule)
+	g.PATCH("/calendars/:id/acl/:ruleId", calenderController.UpdateACLRule)
+	g.DELETE("/calendars/:id/acl/:ruleId", calenderController.DeleteACLRule)
 	g.GET("/events", calenderController.ListEvents)
+	g.GET("/events/sync", calenderController.SyncEvents)
+	g.GET("/events/:id", calenderController.GetEvent)
 	g.POST("/events", calenderController.CreateEvent)
+	g.POST("/events/bulk/delete", calenderController.BulkDeleteEvents)
+	g.POST("/events/bulk/patch", calenderController.BulkPatchEvents)
+	g.POST("/freebusy", calenderController.FreeBusy)
+	g.POST("/meeting-times", calenderController.FindMeetingTimes)
+	g.PATCH("/events/:id", calenderController.PatchEvent)
 	g.POST("/edit/events", calenderController.UpdateEvent)
 	g.POST("/delete/events/:id", calenderController.DeleteEvent)
+	g.POST("/events/:id/rsvp", calenderController.RespondToEvent)
+	g.POST("/events/:id/move", calenderController.MoveEvent)
 }
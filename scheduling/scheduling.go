@@ -0,0 +1,217 @@
+// Package scheduling finds meeting slots from attendees' busy time and
+// working hours. It does no I/O; callers fetch busy intervals beforehand.
+package scheduling
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultStep       = 15 * time.Minute
+	DefaultMaxResults = 10
+)
+
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (i Interval) overlaps(o Interval) bool {
+	return i.Start.Before(o.End) && o.Start.Before(i.End)
+}
+
+// WorkingHours is a daily availability window in the attendee's time zone.
+// Start and End are offsets from local midnight; windows crossing midnight
+// are not supported.
+type WorkingHours struct {
+	Days  []time.Weekday // empty means Monday to Friday
+	Start time.Duration
+	End   time.Duration
+}
+
+type Attendee struct {
+	ID       string
+	Optional bool
+	// Location is used to evaluate working hours; nil means UTC.
+	Location *time.Location
+	// WorkingHours nil means the attendee is available at any time.
+	WorkingHours *WorkingHours
+	Busy         []Interval
+}
+
+type Request struct {
+	Attendees []Attendee
+	Duration  time.Duration
+	Window    Interval
+	// Step is the spacing of candidate start times; zero means DefaultStep.
+	Step time.Duration
+	// Buffer is the gap a slot should keep from required attendees' meetings.
+	Buffer time.Duration
+	// MaxResults caps the number of slots; zero means DefaultMaxResults.
+	MaxResults int
+}
+
+type Slot struct {
+	Start time.Time
+	End   time.Time
+	// OptionalConflicts lists the optional attendees who are busy or outside
+	// their working hours during the slot.
+	OptionalConflicts []string
+	// BufferConflicts counts required attendees with a meeting closer than
+	// the requested buffer.
+	BufferConflicts int
+}
+
+// FindSlots returns candidate slots in which every required attendee is free
+// and within working hours. Slots are ranked by fewest optional conflicts,
+// then fewest buffer conflicts, then earliest start.
+func FindSlots(req Request) ([]Slot, error) {
+	if req.Duration <= 0 {
+		return nil, errors.New("duration must be positive")
+	}
+	if !req.Window.End.After(req.Window.Start) {
+		return nil, errors.New("window end must be after window start")
+	}
+	if req.Buffer < 0 {
+		return nil, errors.New("buffer cannot be negative")
+	}
+	for _, a := range req.Attendees {
+		if wh := a.WorkingHours; wh != nil && (wh.Start < 0 || wh.End > 24*time.Hour || wh.End <= wh.Start) {
+			return nil, fmt.Errorf("invalid working hours for %s", a.ID)
+		}
+	}
+
+	step := req.Step
+	if step <= 0 {
+		step = DefaultStep
+	}
+	maxResults := req.MaxResults
+	if maxResults <= 0 {
+		maxResults = DefaultMaxResults
+	}
+
+	// Align candidates to the step so slots start on round times.
+	start := req.Window.Start.Truncate(step)
+	if start.Before(req.Window.Start) {
+		start = start.Add(step)
+	}
+
+	var slots []Slot
+	for t := start; !t.Add(req.Duration).After(req.Window.End); t = t.Add(step) {
+		slot, ok := evaluate(req, Interval{Start: t, End: t.Add(req.Duration)})
+		if ok {
+			slots = append(slots, slot)
+		}
+	}
+
+	sort.SliceStable(slots, func(i, j int) bool {
+		if len(slots[i].OptionalConflicts) != len(slots[j].OptionalConflicts) {
+			return len(slots[i].OptionalConflicts) < len(slots[j].OptionalConflicts)
+		}
+		if slots[i].BufferConflicts != slots[j].BufferConflicts {
+			return slots[i].BufferConflicts < slots[j].BufferConflicts
+		}
+		return slots[i].Start.Before(slots[j].Start)
+	})
+	if len(slots) > maxResults {
+		slots = slots[:maxResults]
+	}
+	return slots, nil
+}
+
+// evaluate scores one candidate; ok is false when a required attendee cannot
+// attend.
+func evaluate(req Request, candidate Interval) (Slot, bool) {
+	slot := Slot{Start: candidate.Start, End: candidate.End, OptionalConflicts: []string{}}
+	padded := Interval{Start: candidate.Start.Add(-req.Buffer), End: candidate.End.Add(req.Buffer)}
+
+	for _, a := range req.Attendees {
+		available := withinWorkingHours(a, candidate) && !overlapsAny(a.Busy, candidate)
+		if a.Optional {
+			if !available {
+				slot.OptionalConflicts = append(slot.OptionalConflicts, a.ID)
+			}
+			continue
+		}
+		if !available {
+			return Slot{}, false
+		}
+		if req.Buffer > 0 && overlapsAny(a.Busy, padded) {
+			slot.BufferConflicts++
+		}
+	}
+	return slot, true
+}
+
+func withinWorkingHours(a Attendee, slot Interval) bool {
+	wh := a.WorkingHours
+	if wh == nil {
+		return true
+	}
+	loc := a.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	local := slot.Start.In(loc)
+	if !workday(wh.Days, local.Weekday()) {
+		return false
+	}
+
+	// time.Date normalises the minute offset, which keeps wall-clock hours
+	// correct on daylight saving transition days.
+	y, m, d := local.Date()
+	dayStart := time.Date(y, m, d, 0, int(wh.Start/time.Minute), 0, 0, loc)
+	dayEnd := time.Date(y, m, d, 0, int(wh.End/time.Minute), 0, 0, loc)
+	return !slot.Start.Before(dayStart) && !slot.End.After(dayEnd)
+}
+
+func workday(days []time.Weekday, day time.Weekday) bool {
+	if len(days) == 0 {
+		return day != time.Saturday && day != time.Sunday
+	}
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func overlapsAny(busy []Interval, slot Interval) bool {
+	for _, b := range busy {
+		if b.overlaps(slot) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseClock parses a "15:04" time of day into an offset from midnight.
+// "24:00" is accepted as the end of the day.
+func ParseClock(value string) (time.Duration, error) {
+	if value == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// ParseWeekday parses an English weekday name such as "monday" or "Mon".
+func ParseWeekday(value string) (time.Weekday, error) {
+	name := strings.ToLower(value)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if name == full || name == full[:3] {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", value)
+}
@@ -0,0 +1,321 @@
+package scheduling
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load location %s: %v", name, err)
+	}
+	return loc
+}
+
+func at(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func window(from, to string) Interval {
+	return Interval{Start: at(from), End: at(to)}
+}
+
+func nineToFive(days ...time.Weekday) *WorkingHours {
+	return &WorkingHours{Days: days, Start: 9 * time.Hour, End: 17 * time.Hour}
+}
+
+func slotStarts(slots []Slot) []string {
+	starts := []string{}
+	for _, s := range slots {
+		starts = append(starts, s.Start.UTC().Format(time.RFC3339))
+	}
+	return starts
+}
+
+func TestFindSlots(t *testing.T) {
+	newYork := loadLocation(t, "America/New_York")
+	berlin := loadLocation(t, "Europe/Berlin")
+	kolkata := loadLocation(t, "Asia/Kolkata")
+
+	tests := []struct {
+		name string
+		req  Request
+		want []string
+	}{
+		{
+			name: "working hours overlap across zones",
+			req: Request{
+				Attendees: []Attendee{
+					{ID: "ny", Location: newYork, WorkingHours: nineToFive()},
+					{ID: "berlin", Location: berlin, WorkingHours: nineToFive()},
+				},
+				Duration: time.Hour,
+				Step:     30 * time.Minute,
+				// Monday: New York opens at 14:00Z, Berlin closes at 16:00Z.
+				Window: window("2030-01-07T00:00:00Z", "2030-01-08T00:00:00Z"),
+			},
+			want: []string{"2030-01-07T14:00:00Z", "2030-01-07T14:30:00Z", "2030-01-07T15:00:00Z"},
+		},
+		{
+			name: "half hour zone offset",
+			req: Request{
+				Attendees: []Attendee{{ID: "kolkata", Location: kolkata, WorkingHours: &WorkingHours{Start: 9 * time.Hour, End: 10 * time.Hour}}},
+				Duration:  30 * time.Minute,
+				Step:      30 * time.Minute,
+				Window:    window("2030-01-07T00:00:00Z", "2030-01-08T00:00:00Z"),
+			},
+			want: []string{"2030-01-07T03:30:00Z", "2030-01-07T04:00:00Z"},
+		},
+		{
+			name: "weekends are off by default",
+			req: Request{
+				Attendees: []Attendee{{ID: "ny", Location: newYork, WorkingHours: nineToFive()}},
+				Duration:  time.Hour,
+				Window:    window("2030-01-05T00:00:00Z", "2030-01-07T00:00:00Z"),
+			},
+			want: []string{},
+		},
+		{
+			name: "day DST ends",
+			req: Request{
+				Attendees:  []Attendee{{ID: "berlin", Location: berlin, WorkingHours: nineToFive(time.Sunday)}},
+				Duration:   4 * time.Hour,
+				Step:       4 * time.Hour,
+				MaxResults: 10,
+				// 09:00-17:00 CET is 08:00-16:00Z after the clocks go back.
+				Window: window("2030-10-27T00:00:00Z", "2030-10-28T00:00:00Z"),
+			},
+			want: []string{"2030-10-27T08:00:00Z", "2030-10-27T12:00:00Z"},
+		},
+		{
+			name: "day DST starts",
+			req: Request{
+				Attendees: []Attendee{{ID: "berlin", Location: berlin, WorkingHours: nineToFive(time.Sunday)}},
+				Duration:  time.Hour,
+				Step:      time.Hour,
+				// 09:00-17:00 CEST is 07:00-15:00Z.
+				Window: window("2030-03-31T00:00:00Z", "2030-04-01T00:00:00Z"),
+			},
+			want: []string{
+				"2030-03-31T07:00:00Z", "2030-03-31T08:00:00Z", "2030-03-31T09:00:00Z", "2030-03-31T10:00:00Z",
+				"2030-03-31T11:00:00Z", "2030-03-31T12:00:00Z", "2030-03-31T13:00:00Z", "2030-03-31T14:00:00Z",
+			},
+		},
+		{
+			name: "busy time of required attendees is excluded",
+			req: Request{
+				Attendees: []Attendee{
+					{ID: "a", Busy: []Interval{window("2030-01-07T09:00:00Z", "2030-01-07T10:00:00Z")}},
+					{ID: "b", Busy: []Interval{window("2030-01-07T10:30:00Z", "2030-01-07T11:00:00Z")}},
+				},
+				Duration: 30 * time.Minute,
+				Step:     30 * time.Minute,
+				Window:   window("2030-01-07T09:00:00Z", "2030-01-07T11:30:00Z"),
+			},
+			want: []string{"2030-01-07T10:00:00Z", "2030-01-07T11:00:00Z"},
+		},
+		{
+			name: "slots near meetings rank after buffered ones",
+			req: Request{
+				Attendees: []Attendee{{ID: "a", Busy: []Interval{window("2030-01-07T10:00:00Z", "2030-01-07T11:00:00Z")}}},
+				Duration:  30 * time.Minute,
+				Step:      30 * time.Minute,
+				Buffer:    15 * time.Minute,
+				Window:    window("2030-01-07T09:00:00Z", "2030-01-07T12:00:00Z"),
+			},
+			want: []string{"2030-01-07T09:00:00Z", "2030-01-07T11:30:00Z", "2030-01-07T09:30:00Z", "2030-01-07T11:00:00Z"},
+		},
+		{
+			name: "optional attendees only change the ranking",
+			req: Request{
+				Attendees: []Attendee{
+					{ID: "required"},
+					{ID: "busy", Optional: true, Busy: []Interval{window("2030-01-07T09:00:00Z", "2030-01-07T10:00:00Z")}},
+					{ID: "late", Optional: true, WorkingHours: &WorkingHours{Start: 9*time.Hour + 30*time.Minute, End: 17 * time.Hour}},
+				},
+				Duration: 30 * time.Minute,
+				Step:     30 * time.Minute,
+				Window:   window("2030-01-07T09:00:00Z", "2030-01-07T10:30:00Z"),
+			},
+			want: []string{"2030-01-07T10:00:00Z", "2030-01-07T09:30:00Z", "2030-01-07T09:00:00Z"},
+		},
+		{
+			name: "starts are aligned to the step",
+			req: Request{
+				Attendees: []Attendee{{ID: "a"}},
+				Duration:  30 * time.Minute,
+				Window:    window("2030-01-07T09:07:00Z", "2030-01-07T10:00:00Z"),
+			},
+			want: []string{"2030-01-07T09:15:00Z", "2030-01-07T09:30:00Z"},
+		},
+		{
+			name: "aligned window start is kept",
+			req: Request{
+				Attendees: []Attendee{{ID: "a"}},
+				Duration:  time.Hour,
+				Step:      time.Hour,
+				Window:    window("2030-01-07T09:00:00Z", "2030-01-07T11:00:00Z"),
+			},
+			want: []string{"2030-01-07T09:00:00Z", "2030-01-07T10:00:00Z"},
+		},
+		{
+			name: "max results",
+			req: Request{
+				Attendees:  []Attendee{{ID: "a"}},
+				Duration:   30 * time.Minute,
+				MaxResults: 3,
+				Window:     window("2030-01-07T09:00:00Z", "2030-01-07T17:00:00Z"),
+			},
+			want: []string{"2030-01-07T09:00:00Z", "2030-01-07T09:15:00Z", "2030-01-07T09:30:00Z"},
+		},
+		{
+			name: "duration longer than the window",
+			req: Request{
+				Attendees: []Attendee{{ID: "a"}},
+				Duration:  2 * time.Hour,
+				Window:    window("2030-01-07T09:00:00Z", "2030-01-07T10:00:00Z"),
+			},
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slots, err := FindSlots(tt.req)
+			if err != nil {
+				t.Fatalf("FindSlots: %v", err)
+			}
+			if got := slotStarts(slots); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("slot starts = %v, want %v", got, tt.want)
+			}
+			for _, s := range slots {
+				if s.End.Sub(s.Start) != tt.req.Duration {
+					t.Errorf("slot %s lasts %s, want %s", s.Start, s.End.Sub(s.Start), tt.req.Duration)
+				}
+			}
+		})
+	}
+}
+
+func TestFindSlotsConflictDetails(t *testing.T) {
+	slots, err := FindSlots(Request{
+		Attendees: []Attendee{
+			{ID: "a", Busy: []Interval{window("2030-01-07T10:00:00Z", "2030-01-07T11:00:00Z")}},
+			{ID: "b", Busy: []Interval{window("2030-01-07T08:00:00Z", "2030-01-07T09:40:00Z")}},
+			{ID: "opt", Optional: true, Busy: []Interval{window("2030-01-07T09:30:00Z", "2030-01-07T09:45:00Z")}},
+		},
+		Duration: 15 * time.Minute,
+		Buffer:   10 * time.Minute,
+		Window:   window("2030-01-07T09:45:00Z", "2030-01-07T10:00:00Z"),
+	})
+	if err != nil {
+		t.Fatalf("FindSlots: %v", err)
+	}
+	if len(slots) != 1 {
+		t.Fatalf("got %d slots, want 1", len(slots))
+	}
+	if slots[0].BufferConflicts != 2 {
+		t.Errorf("BufferConflicts = %d, want 2", slots[0].BufferConflicts)
+	}
+	if len(slots[0].OptionalConflicts) != 0 {
+		t.Errorf("OptionalConflicts = %v, want none", slots[0].OptionalConflicts)
+	}
+}
+
+func TestFindSlotsDefaultMaxResults(t *testing.T) {
+	slots, err := FindSlots(Request{
+		Attendees: []Attendee{{ID: "a"}},
+		Duration:  15 * time.Minute,
+		Window:    window("2030-01-07T00:00:00Z", "2030-01-08T00:00:00Z"),
+	})
+	if err != nil {
+		t.Fatalf("FindSlots: %v", err)
+	}
+	if len(slots) != DefaultMaxResults {
+		t.Errorf("got %d slots, want %d", len(slots), DefaultMaxResults)
+	}
+}
+
+func TestFindSlotsValidation(t *testing.T) {
+	day := window("2030-01-07T00:00:00Z", "2030-01-08T00:00:00Z")
+	tests := []struct {
+		name string
+		req  Request
+	}{
+		{"zero duration", Request{Window: day}},
+		{"empty window", Request{Duration: time.Hour, Window: window("2030-01-07T10:00:00Z", "2030-01-07T10:00:00Z")}},
+		{"negative buffer", Request{Duration: time.Hour, Window: day, Buffer: -time.Minute}},
+		{"working hours end before start", Request{Duration: time.Hour, Window: day, Attendees: []Attendee{
+			{ID: "a", WorkingHours: &WorkingHours{Start: 17 * time.Hour, End: 9 * time.Hour}},
+		}}},
+		{"working hours past midnight", Request{Duration: time.Hour, Window: day, Attendees: []Attendee{
+			{ID: "a", WorkingHours: &WorkingHours{Start: 9 * time.Hour, End: 25 * time.Hour}},
+		}}},
+	}
+	for _, tt := range tests {
+		if _, err := FindSlots(tt.req); err == nil {
+			t.Errorf("%s: FindSlots succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "00:00", want: 0},
+		{value: "09:30", want: 9*time.Hour + 30*time.Minute},
+		{value: "17:05", want: 17*time.Hour + 5*time.Minute},
+		{value: "24:00", want: 24 * time.Hour},
+		{value: "24:30", wantErr: true},
+		{value: "12:60", wantErr: true},
+		{value: "noon", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseClock(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseClock(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseClock(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseWeekday(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Weekday
+		wantErr bool
+	}{
+		{value: "monday", want: time.Monday},
+		{value: "Mon", want: time.Monday},
+		{value: "SUNDAY", want: time.Sunday},
+		{value: "sat", want: time.Saturday},
+		{value: "Thu", want: time.Thursday},
+		{value: "mo", wantErr: true},
+		{value: "mondays", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseWeekday(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseWeekday(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseWeekday(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"backend/adapters"
+	"backend/constants"
+	"backend/models"
+	"backend/utils"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// Create validates the events, checks them for conflicts concurrently and
+// inserts the accepted ones through the provider's batch call, reporting the
+// outcome of each one. Overlaps found for events with a "warn" or "reject"
+// conflict policy are reported even when creation fails. With atomic set
+// nothing is created when an event is invalid or rejected, and events
+// already created are deleted again when another one fails.
+func (s *CalendarService) Create(token string, newEvents []models.CreateEvent, atomic bool) ([]models.EventResult, error) {
+	results := make([]models.EventResult, len(newEvents))
+	prepared := make([]*calendar.Event, len(newEvents))
+	for i, e := range newEvents {
+		results[i] = models.EventResult{Index: i, Summary: e.Summary}
+		if err := validateCreateEvent(e); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		prepared[i] = utils.AdjustEvent(e)
+		results[i].Conflicts = batchConflicts(newEvents, prepared, i)
+	}
+
+	forEach(len(newEvents), func(i int) {
+		if prepared[i] != nil && !s.acceptEvent(token, newEvents[i], prepared[i], &results[i]) {
+			prepared[i] = nil
+		}
+	})
+	if atomic && failed(results) {
+		return results, createError(results)
+	}
+
+	var ops []adapters.EventOp
+	var index []int
+	for i, ev := range prepared {
+		if ev == nil {
+			continue
+		}
+		ops = append(ops, adapters.EventOp{
+			Kind:       adapters.BatchInsert,
+			CalendarID: calendarOrPrimary(newEvents[i].CalendarID),
+			Event:      ev,
+			Opts:       models.WriteOptions{SendUpdates: newEvents[i].SendUpdates},
+		})
+		index = append(index, i)
+	}
+	s.runBatch(token, ops, index, results)
+
+	if !failed(results) {
+		return results, nil
+	}
+	if atomic {
+		s.rollback(token, newEvents, results)
+	}
+	return results, createError(results)
+}
+
+// acceptEvent applies the conflict policy of e, reporting whether it may be
+// created.
+func (s *CalendarService) acceptEvent(token string, e models.CreateEvent, ev *calendar.Event, result *models.EventResult) bool {
+	found, err := s.checkConflicts(token, calendarOrPrimary(e.CalendarID), ev, e.ConflictPolicy)
+	if err != nil {
+		result.Error = err.Error()
+		return false
+	}
+	result.Conflicts = append(result.Conflicts, found...)
+	if len(result.Conflicts) > 0 && e.ConflictPolicy == constants.ConflictPolicyReject {
+		result.Rejected = true
+		result.Error = ErrEventConflict.Error()
+		return false
+	}
+	return true
+}
+
+// rollback deletes the events created by a failed all-or-nothing batch.
+func (s *CalendarService) rollback(token string, newEvents []models.CreateEvent, results []models.EventResult) {
+	var ops []adapters.EventOp
+	var index []int
+	for i := range results {
+		if results[i].EventID == "" {
+			continue
+		}
+		ops = append(ops, adapters.EventOp{
+			Kind:       adapters.BatchDelete,
+			CalendarID: calendarOrPrimary(newEvents[i].CalendarID),
+			EventID:    results[i].EventID,
+			Opts:       models.WriteOptions{SendUpdates: newEvents[i].SendUpdates},
+		})
+		index = append(index, i)
+	}
+
+	for k, r := range s.adapter.BatchEvents(token, ops) {
+		i := index[k]
+		if r.Err != nil {
+			results[i].Error = fmt.Sprintf("rollback failed: %v", r.Err)
+			continue
+		}
+		results[i].RolledBack = true
+	}
+}
+
+// runBatch sends ops in one provider batch and records the outcome of op k
+// in results[index[k]].
+func (s *CalendarService) runBatch(token string, ops []adapters.EventOp, index []int, results []models.EventResult) {
+	if len(ops) == 0 {
+		return
+	}
+	for k, r := range s.adapter.BatchEvents(token, ops) {
+		i := index[k]
+		if r.Err != nil {
+			results[i].Error = r.Err.Error()
+			continue
+		}
+		if r.Event != nil {
+			results[i].EventID = r.Event.Id
+		}
+	}
+}
+
+// batchConflicts returns the earlier events of the same batch that event i
+// overlaps. They are created concurrently, so the provider cannot report
+// them yet.
+func batchConflicts(newEvents []models.CreateEvent, prepared []*calendar.Event, i int) []models.Event {
+	policy := newEvents[i].ConflictPolicy
+	if policy == "" || policy == constants.ConflictPolicyAllow {
+		return nil
+	}
+
+	var conflicts []models.Event
+	ev := utils.ToEvent(prepared[i])
+	for j := 0; j < i; j++ {
+		if prepared[j] == nil || calendarOrPrimary(newEvents[j].CalendarID) != calendarOrPrimary(newEvents[i].CalendarID) {
+			continue
+		}
+		other := utils.ToEvent(prepared[j])
+		if other.StartTime.Before(ev.EndTime) && ev.StartTime.Before(other.EndTime) {
+			conflicts = append(conflicts, other)
+		}
+	}
+	return conflicts
+}
+
+// BulkDelete deletes the selected events. Deleting an instance cancels just
+// that occurrence; deleting a series master removes the whole series.
+func (s *CalendarService) BulkDelete(token string, d models.BulkDelete) (models.BulkResult, error) {
+	if err := validateSendUpdates(d.SendUpdates); err != nil {
+		return models.BulkResult{}, err
+	}
+	calendarID := calendarOrPrimary(d.CalendarID)
+	targets, results, err := s.selectEvents(token, d.BulkSelection)
+	if err != nil || d.DryRun {
+		return models.BulkResult{DryRun: d.DryRun, Results: results}, err
+	}
+
+	var ops []adapters.EventOp
+	var index []int
+	for i, target := range targets {
+		if target == nil {
+			continue
+		}
+		ops = append(ops, adapters.EventOp{
+			Kind:       adapters.BatchDelete,
+			CalendarID: calendarID,
+			EventID:    target.ID,
+			Opts:       models.WriteOptions{SendUpdates: d.SendUpdates},
+		})
+		index = append(index, i)
+	}
+	s.runBatch(token, ops, index, results)
+	return models.BulkResult{Results: results}, nil
+}
+
+// BulkPatch applies the same change to every selected event. The current
+// events are fetched concurrently and the patches sent as one batch.
+// All-day events can only be shifted by whole days; the others fail and are
+// left unchanged.
+func (s *CalendarService) BulkPatch(token string, p models.BulkPatch) (models.BulkResult, error) {
+	if p.ShiftMinutes == 0 && p.Location == nil {
+		return models.BulkResult{}, fmt.Errorf("shift_minutes or location is required")
+	}
+	if err := validateSendUpdates(p.SendUpdates); err != nil {
+		return models.BulkResult{}, err
+	}
+	targets, results, err := s.selectEvents(token, p.BulkSelection)
+	if err != nil || p.DryRun {
+		return models.BulkResult{DryRun: p.DryRun, Results: results}, err
+	}
+
+	calendarID := calendarOrPrimary(p.CalendarID)
+	patches := make([]*calendar.Event, len(targets))
+	forEach(len(targets), func(i int) {
+		if targets[i] == nil {
+			return
+		}
+		existing, err := s.adapter.GetEvent(token, calendarID, targets[i].ID)
+		if err != nil {
+			results[i].Error = err.Error()
+			return
+		}
+		allDay := existing.Start != nil && existing.Start.Date != ""
+		if allDay && p.ShiftMinutes%minutesPerDay != 0 {
+			results[i].Error = errPartialDayShift.Error()
+			return
+		}
+		change := models.PatchEvent{Location: p.Location}
+		if p.ShiftMinutes != 0 && !allDay {
+			start := utils.ParseDateTime(existing.Start).Add(time.Duration(p.ShiftMinutes) * time.Minute)
+			change.StartTime = &start
+		}
+		patch := utils.PatchToEvent(existing, change)
+		if allDay && p.ShiftMinutes != 0 {
+			days := p.ShiftMinutes / minutesPerDay
+			patch.Start = shiftDate(existing.Start, days)
+			patch.End = shiftDate(existing.End, days)
+		}
+		patches[i] = patch
+	})
+
+	var ops []adapters.EventOp
+	var index []int
+	for i, patch := range patches {
+		if patch == nil {
+			continue
+		}
+		ops = append(ops, adapters.EventOp{
+			Kind:       adapters.BatchPatch,
+			CalendarID: calendarID,
+			EventID:    targets[i].ID,
+			Event:      patch,
+			Opts:       models.WriteOptions{SendUpdates: p.SendUpdates},
+		})
+		index = append(index, i)
+	}
+	s.runBatch(token, ops, index, results)
+	return models.BulkResult{Results: results}, nil
+}
+
+const minutesPerDay = 24 * 60
+
+var errPartialDayShift = errors.New("all-day events can only be shifted by whole days")
+
+// shiftDate moves the date of an all-day start or end by days.
+func shiftDate(dt *calendar.EventDateTime, days int) *calendar.EventDateTime {
+	date, _ := time.Parse("2006-01-02", dt.Date)
+	return &calendar.EventDateTime{Date: date.AddDate(0, 0, days).Format("2006-01-02")}
+}
+
+// selectEvents resolves a bulk selection. Unknown IDs get an error result and
+// a nil target; the results of the found events carry their ID and summary.
+func (s *CalendarService) selectEvents(token string, sel models.BulkSelection) ([]*models.Event, []models.EventResult, error) {
+	calendarID := calendarOrPrimary(sel.CalendarID)
+
+	if len(sel.IDs) > 0 {
+		targets := make([]*models.Event, len(sel.IDs))
+		results := make([]models.EventResult, len(sel.IDs))
+		forEach(len(sel.IDs), func(i int) {
+			results[i] = models.EventResult{Index: i, EventID: sel.IDs[i]}
+			ev, err := s.adapter.GetEvent(token, calendarID, sel.IDs[i])
+			if err != nil {
+				results[i].Error = err.Error()
+				return
+			}
+			target := utils.ToEvent(ev)
+			targets[i] = &target
+			results[i].Summary = target.Summary
+		})
+		return targets, results, nil
+	}
+
+	// A query without a time range would select ten years of events.
+	if sel.From == "" || sel.To == "" {
+		return nil, nil, fmt.Errorf("ids or a from and to time range are required")
+	}
+	page, err := s.ListEvents(token, models.EventQuery{
+		CalendarID:   calendarID,
+		From:         sel.From,
+		To:           sel.To,
+		Q:            sel.Q,
+		SingleEvents: true,
+		PageSize:     constants.MaxEventPageSize,
+		FetchAll:     true,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if page.NextPageToken != "" {
+		return nil, nil, fmt.Errorf("query selects too many events; narrow the time range")
+	}
+
+	targets := make([]*models.Event, len(page.Events))
+	results := make([]models.EventResult, len(page.Events))
+	for i := range page.Events {
+		targets[i] = &page.Events[i]
+		results[i] = models.EventResult{Index: i, EventID: page.Events[i].ID, Summary: page.Events[i].Summary}
+	}
+	return targets, results, nil
+}
+
+// forEach calls fn for 0..n-1 with at most constants.BulkConcurrency calls
+// running at once.
+func forEach(n int, fn func(i int)) {
+	sem := make(chan struct{}, constants.BulkConcurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func failed(results []models.EventResult) bool {
+	for _, r := range results {
+		if r.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func createError(results []models.EventResult) error {
+	var failedEvents []string
+	for _, r := range results {
+		if r.Error != "" {
+			failedEvents = append(failedEvents, r.Summary)
+		}
+	}
+	return fmt.Errorf("Failed to create the following events: %v", failedEvents)
+}
+
+func validateCreateEvent(e models.CreateEvent) error {
+	if err := validateEvent(e.TimeZone, e.Recurrence, e.ConflictPolicy); err != nil {
+		return err
+	}
+	if err := validateSendUpdates(e.SendUpdates); err != nil {
+		return err
+	}
+	return validateReminders(e.Reminders)
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"backend/constants"
+	"backend/models"
+	"testing"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// createAllDay stores an all-day event on the primary calendar of s and
+// returns its ID.
+func createAllDay(t *testing.T, s *CalendarService, start, end string) string {
+	t.Helper()
+	ev, err := s.adapter.CreateEvent("token", constants.PrimaryCalendarID, &calendar.Event{
+		Summary:  "offsite",
+		Location: "Office",
+		Start:    &calendar.EventDateTime{Date: start},
+		End:      &calendar.EventDateTime{Date: end},
+	}, models.WriteOptions{})
+	if err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	return ev.Id
+}
+
+func TestBulkPatchAllDayShift(t *testing.T) {
+	room := "Room 2"
+	tests := []struct {
+		name         string
+		shift        int
+		location     *string
+		wantError    bool
+		wantStart    string
+		wantEnd      string
+		wantLocation string
+	}{
+		{name: "whole days", shift: 2 * minutesPerDay, wantStart: "2030-03-06", wantEnd: "2030-03-07", wantLocation: "Office"},
+		{name: "whole days back", shift: -minutesPerDay, wantStart: "2030-03-03", wantEnd: "2030-03-04", wantLocation: "Office"},
+		{name: "location only", location: &room, wantStart: "2030-03-04", wantEnd: "2030-03-05", wantLocation: room},
+		{name: "partial day", shift: 30, wantError: true, wantStart: "2030-03-04", wantEnd: "2030-03-05", wantLocation: "Office"},
+		{name: "partial day with location", shift: 60, location: &room, wantError: true, wantStart: "2030-03-04", wantEnd: "2030-03-05", wantLocation: "Office"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemoryService()
+			id := createAllDay(t, s, "2030-03-04", "2030-03-05")
+
+			result, err := s.BulkPatch("token", models.BulkPatch{
+				BulkSelection: models.BulkSelection{IDs: []string{id}},
+				ShiftMinutes:  tt.shift,
+				Location:      tt.location,
+			})
+			if err != nil {
+				t.Fatalf("BulkPatch: %v", err)
+			}
+			if got := result.Results[0].Error; (got != "") != tt.wantError {
+				t.Errorf("result error = %q, wantError %v", got, tt.wantError)
+			}
+
+			ev, err := s.adapter.GetEvent("token", constants.PrimaryCalendarID, id)
+			if err != nil {
+				t.Fatalf("GetEvent: %v", err)
+			}
+			if ev.Start.Date != tt.wantStart || ev.End.Date != tt.wantEnd {
+				t.Errorf("dates = %s..%s, want %s..%s", ev.Start.Date, ev.End.Date, tt.wantStart, tt.wantEnd)
+			}
+			if ev.Location != tt.wantLocation {
+				t.Errorf("location = %q, want %q", ev.Location, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestBulkPatchPartialDayShiftOnlyFailsAllDayEvents(t *testing.T) {
+	s := newMemoryService()
+	allDay := createAllDay(t, s, "2030-03-04", "2030-03-05")
+	timed, err := s.adapter.CreateEvent("token", constants.PrimaryCalendarID, &calendar.Event{
+		Summary: "standup",
+		Start:   &calendar.EventDateTime{DateTime: "2030-03-04T09:00:00Z"},
+		End:     &calendar.EventDateTime{DateTime: "2030-03-04T09:15:00Z"},
+	}, models.WriteOptions{})
+	if err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+
+	result, err := s.BulkPatch("token", models.BulkPatch{
+		BulkSelection: models.BulkSelection{IDs: []string{allDay, timed.Id}},
+		ShiftMinutes:  30,
+	})
+	if err != nil {
+		t.Fatalf("BulkPatch: %v", err)
+	}
+	if result.Results[0].Error != errPartialDayShift.Error() {
+		t.Errorf("all-day result error = %q, want %q", result.Results[0].Error, errPartialDayShift)
+	}
+	if result.Results[1].Error != "" {
+		t.Errorf("timed result error = %q", result.Results[1].Error)
+	}
+
+	moved, _ := s.adapter.GetEvent("token", constants.PrimaryCalendarID, timed.Id)
+	if moved.Start.DateTime != "2030-03-04T09:30:00Z" || moved.End.DateTime != "2030-03-04T09:45:00Z" {
+		t.Errorf("timed event = %s..%s, want 09:30..09:45", moved.Start.DateTime, moved.End.DateTime)
+	}
+}
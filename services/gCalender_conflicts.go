@@ -0,0 +1,136 @@
+package services
+
+import (
+	"backend/constants"
+	"backend/models"
+	"backend/utils"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// ErrEventConflict is returned when an edit with the "reject" conflict
+// policy overlaps existing events.
+var ErrEventConflict = errors.New("event conflicts with existing events")
+
+// ErrNotInvited is returned when the user answers an event they are not
+// an attendee of.
+var ErrNotInvited = errors.New("you are not an attendee of this event")
+
+// ErrMoveInstance is returned when a single instance of a recurring event is
+// moved without asking for the whole series.
+var ErrMoveInstance = errors.New(`instances cannot be moved on their own; use scope "all" to move the whole series`)
+
+// ErrAttendeeTimeZone is returned when a meeting attendee has working hours
+// but no time zone to read them in.
+var ErrAttendeeTimeZone = errors.New("time_zone is required for attendees with working_hours")
+
+// StaleEventError is returned when an If-Match ETag no longer matches the
+// stored event. Current is the version the client should merge with.
+type StaleEventError struct {
+	Current models.Event
+}
+
+func (e *StaleEventError) Error() string {
+	return "event has been modified since it was fetched"
+}
+
+// checkETag compares etag with the current version of eventID. Writes that
+// go to the series master cannot pass the instance's ETag to the provider,
+// so they check it up front instead.
+func (s *CalendarService) checkETag(token, calendarID, eventID, etag string) error {
+	if etag == "" {
+		return nil
+	}
+	current, err := s.adapter.GetEvent(token, calendarID, eventID)
+	if err != nil {
+		return err
+	}
+	if current.Etag != etag {
+		return &StaleEventError{Current: utils.ToEvent(current)}
+	}
+	return nil
+}
+
+// staleEvent turns a provider's 412 for eventID into a StaleEventError
+// carrying the current event.
+func (s *CalendarService) staleEvent(token, calendarID, eventID string, err error) error {
+	if !utils.HasStatusCode(err, http.StatusPreconditionFailed) {
+		return err
+	}
+	current, getErr := s.adapter.GetEvent(token, calendarID, eventID)
+	if getErr != nil {
+		return err
+	}
+	return &StaleEventError{Current: utils.ToEvent(current)}
+}
+
+// checkConflicts returns the events in calendarID that overlap ev, unless the
+// policy allows overlaps. Recurring events are checked by their first
+// occurrence; ev itself and its series are never reported, and neither are
+// events that do not block time or that the user declined.
+func (s *CalendarService) checkConflicts(token, calendarID string, ev *calendar.Event, policy string) ([]models.Event, error) {
+	if policy == "" || policy == constants.ConflictPolicyAllow {
+		return nil, nil
+	}
+
+	start, end := s.eventSpan(utils.ToEvent(ev))
+	// All-day events are stored by date, so the search is widened by a day
+	// on each side and the exact overlap is decided below.
+	page, err := s.adapter.ListEvents(token, models.EventQuery{
+		CalendarID:   calendarID,
+		From:         start.AddDate(0, 0, -1).Format(time.RFC3339),
+		To:           end.AddDate(0, 0, 1).Format(time.RFC3339),
+		SingleEvents: true,
+		PageSize:     constants.MaxEventPageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []models.Event
+	for _, existing := range page.Events {
+		if ev.Id != "" && (existing.ID == ev.Id || existing.RecurringEventID == ev.Id) {
+			continue
+		}
+		if existing.Status == constants.EventStatusCancelled ||
+			existing.Transparency == constants.TransparencyTransparent ||
+			s.declinedByUser(existing) {
+			continue
+		}
+		existingStart, existingEnd := s.eventSpan(existing)
+		if existingStart.Before(end) && start.Before(existingEnd) {
+			conflicts = append(conflicts, existing)
+		}
+	}
+	return conflicts, nil
+}
+
+// eventSpan returns the time e occupies. All-day events cover their dates
+// from midnight to midnight in the user's time zone.
+func (s *CalendarService) eventSpan(e models.Event) (time.Time, time.Time) {
+	if !e.AllDay {
+		return e.StartTime, e.EndTime
+	}
+	loc := s.userLocation
+	if loc == nil {
+		loc = time.UTC
+	}
+	start, _ := time.ParseInLocation("2006-01-02", e.StartDate, loc)
+	end, _ := time.ParseInLocation("2006-01-02", e.EndDate, loc)
+	return start, end
+}
+
+// declinedByUser reports whether the signed-in user declined e. As in
+// Respond, the user's attendee entry is found by its self flag or email.
+func (s *CalendarService) declinedByUser(e models.Event) bool {
+	for _, a := range e.Attendees {
+		if a.Self || (s.userEmail != "" && strings.EqualFold(a.Email, s.userEmail)) {
+			return a.ResponseStatus == constants.ResponseDeclined
+		}
+	}
+	return false
+}
@@ -2,21 +2,149 @@ package services
 
 import (
 	"backend/adapters"
+	"backend/constants"
 	"backend/models"
+	"backend/scheduling"
 	"backend/utils"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
+
+	"google.golang.org/api/calendar/v3"
 )
 
 type CalendarService struct {
-	adapter *adapters.GoogleAdapter
+	adapter   adapters.CalendarProvider
+	providers map[string]adapters.CalendarProvider
+
+	// userEmail and userLocation identify the signed-in user for conflict
+	// checks; see ForUser.
+	userEmail    string
+	userLocation *time.Location
+}
+
+func NewCalendarService(adapter adapters.CalendarProvider) *CalendarService {
+	return &CalendarService{
+		adapter:   adapter,
+		providers: map[string]adapters.CalendarProvider{},
+	}
+}
+
+// RegisterProvider makes a provider selectable by name through ForProvider.
+// It is meant to be called during startup, before requests are served.
+func (s *CalendarService) RegisterProvider(name string, provider adapters.CalendarProvider) {
+	s.providers[name] = provider
+}
+
+// ForProvider returns a service bound to the named provider. Unknown or empty
+// names fall back to the provider the service was created with.
+func (s *CalendarService) ForProvider(name string) *CalendarService {
+	provider, ok := s.providers[name]
+	if !ok {
+		return s
+	}
+	bound := *s
+	bound.adapter = provider
+	return &bound
+}
+
+// ForUser returns a service bound to the signed-in user. Conflict checks
+// ignore events the user declined and lay all-day events out in timeZone,
+// or UTC when it is empty or unknown.
+func (s *CalendarService) ForUser(email, timeZone string) *CalendarService {
+	bound := *s
+	bound.userEmail = email
+	bound.userLocation = time.UTC
+	if loc, err := time.LoadLocation(timeZone); err == nil {
+		bound.userLocation = loc
+	}
+	return &bound
+}
+
+func (s *CalendarService) ListCalendars(token string) ([]models.Calendar, error) {
+	return s.adapter.ListCalendars(token)
+}
+
+func (s *CalendarService) CreateCalendar(token string, c models.CreateCalendar) (models.Calendar, error) {
+	if c.Summary == "" {
+		return models.Calendar{}, fmt.Errorf("summary is required")
+	}
+	if c.TimeZone != "" {
+		if err := utils.ValidateTimeZone(c.TimeZone); err != nil {
+			return models.Calendar{}, err
+		}
+	}
+	return s.adapter.CreateCalendar(token, c)
+}
+
+func (s *CalendarService) PatchCalendar(token, calendarID string, c models.EditCalendar) (models.Calendar, error) {
+	if c.Summary != nil && *c.Summary == "" {
+		return models.Calendar{}, fmt.Errorf("summary cannot be empty")
+	}
+	if c.TimeZone != nil {
+		if err := utils.ValidateTimeZone(*c.TimeZone); err != nil {
+			return models.Calendar{}, err
+		}
+	}
+	return s.adapter.PatchCalendar(token, calendarID, c)
+}
+
+func (s *CalendarService) DeleteCalendar(token, calendarID string) error {
+	if calendarID == constants.PrimaryCalendarID {
+		return fmt.Errorf("the primary calendar cannot be deleted")
+	}
+	return s.adapter.DeleteCalendar(token, calendarID)
+}
+
+func (s *CalendarService) ClearCalendar(token, calendarID string) error {
+	return s.adapter.ClearCalendar(token, calendarID)
+}
+
+func (s *CalendarService) ListACLRules(token, calendarID string) ([]models.ACLRule, error) {
+	return s.adapter.ListACLRules(token, calendarID)
+}
+
+func (s *CalendarService) CreateACLRule(token, calendarID string, rule models.CreateACLRule) (models.ACLRule, error) {
+	if err := validateACLRole(rule.Role); err != nil {
+		return models.ACLRule{}, err
+	}
+	switch rule.ScopeType {
+	case constants.ACLScopeUser, constants.ACLScopeGroup:
+		if !strings.Contains(rule.ScopeValue, "@") {
+			return models.ACLRule{}, fmt.Errorf("scope value must be an email address for %s scope", rule.ScopeType)
+		}
+	case constants.ACLScopeDomain:
+		if rule.ScopeValue == "" || strings.Contains(rule.ScopeValue, "@") {
+			return models.ACLRule{}, fmt.Errorf("scope value must be a domain name for domain scope")
+		}
+	default:
+		return models.ACLRule{}, fmt.Errorf("invalid scope type %q", rule.ScopeType)
+	}
+	return s.adapter.CreateACLRule(token, calendarID, rule)
 }
 
-func NewCalendarService(adapter *adapters.GoogleAdapter) *CalendarService {
-	return &CalendarService{adapter: adapter}
+func (s *CalendarService) UpdateACLRule(token, calendarID, ruleID string, rule models.EditACLRule) (models.ACLRule, error) {
+	if err := validateACLRole(rule.Role); err != nil {
+		return models.ACLRule{}, err
+	}
+	return s.adapter.UpdateACLRule(token, calendarID, ruleID, rule)
+}
+
+func (s *CalendarService) DeleteACLRule(token, calendarID, ruleID string) error {
+	return s.adapter.DeleteACLRule(token, calendarID, ruleID)
 }
 
-func (s *CalendarService) ListEvents(token string, query models.EventQuery) ([]models.Event, error) {
+func (s *CalendarService) ListEvents(token string, query models.EventQuery) (models.EventList, error) {
+	query.CalendarID = calendarOrPrimary(query.CalendarID)
+
+	if query.PageSize < 0 || query.PageSize > constants.MaxEventPageSize {
+		return models.EventList{}, fmt.Errorf("page_size must be between 1 and %d", constants.MaxEventPageSize)
+	}
+	if query.PageSize == 0 {
+		query.PageSize = constants.DefaultEventPageSize
+	}
+
 	now := time.Now()
 	rfc3339Time := now.Format(time.RFC3339)
 
@@ -29,34 +157,253 @@ func (s *CalendarService) ListEvents(token string, query models.EventQuery) ([]m
 	if query.To == "" {
 		query.To = tenYearsLaterString
 	}
-	return s.adapter.ListEvents(token, query)
+
+	var loc *time.Location
+	if query.TimeZone != "" {
+		if err := utils.ValidateTimeZone(query.TimeZone); err != nil {
+			return models.EventList{}, err
+		}
+		loc, _ = time.LoadLocation(query.TimeZone)
+	}
+	if err := validateEventQuery(query); err != nil {
+		return models.EventList{}, err
+	}
+
+	page, err := s.adapter.ListEvents(token, query)
+	if err != nil {
+		return models.EventList{}, err
+	}
+
+	// fetch_all keeps following pages, leaving a next page token behind when
+	// the cap is hit so the client can resume from there.
+	for pages := 1; query.FetchAll && page.NextPageToken != "" && pages < constants.MaxFetchAllPages; pages++ {
+		query.PageToken = page.NextPageToken
+		next, err := s.adapter.ListEvents(token, query)
+		if err != nil {
+			return models.EventList{}, err
+		}
+		page.Events = append(page.Events, next.Events...)
+		page.NextPageToken = next.NextPageToken
+	}
+	if query.FetchAll {
+		utils.SortEventsBy(page.Events, query.OrderBy)
+	}
+
+	if loc != nil {
+		utils.EventsInTimeZone(page.Events, loc)
+	}
+	return page, nil
 }
 
-func (s *CalendarService) Create(token string, newEvents []models.CreateEvent) error {
-	var failedEvents []string
-	for _, e := range newEvents {
-		eventToInsert := utils.AdjustEvent(e)
-		err := s.adapter.CreateEvent(token, eventToInsert)
+// SyncEvents returns the changes since query.SyncToken, or every event when
+// no token is given. An expired token falls back to a full resync.
+func (s *CalendarService) SyncEvents(token string, query models.SyncQuery) (models.SyncResult, error) {
+	query.CalendarID = calendarOrPrimary(query.CalendarID)
+
+	result, err := s.syncPages(token, query)
+	if err != nil && query.SyncToken != "" && utils.HasStatusCode(err, http.StatusGone) {
+		query.SyncToken = ""
+		query.PageToken = ""
+		return s.syncPages(token, query)
+	}
+	return result, err
+}
+
+// syncPages follows page tokens up to constants.MaxFetchAllPages so that a
+// sync normally ends with a next sync token.
+func (s *CalendarService) syncPages(token string, query models.SyncQuery) (models.SyncResult, error) {
+	result, err := s.adapter.SyncEvents(token, query)
+	if err != nil {
+		return models.SyncResult{}, err
+	}
+
+	for pages := 1; result.NextPageToken != "" && pages < constants.MaxFetchAllPages; pages++ {
+		query.PageToken = result.NextPageToken
+		next, err := s.adapter.SyncEvents(token, query)
 		if err != nil {
-			failedEvents = append(failedEvents, e.Summary)
-			continue
+			return models.SyncResult{}, err
 		}
+		result.Events = append(result.Events, next.Events...)
+		result.NextPageToken = next.NextPageToken
+		result.NextSyncToken = next.NextSyncToken
 	}
-	if len(failedEvents) > 0 {
-		return fmt.Errorf("Failed to create the following events: %v", failedEvents)
+	return result, nil
+}
+
+// FreeBusy returns the busy time of every requested calendar together with
+// the merged busy intervals and the free gaps between them.
+func (s *CalendarService) FreeBusy(token string, query models.FreeBusyQuery) (models.FreeBusyResult, error) {
+	if len(query.Calendars) == 0 {
+		return models.FreeBusyResult{}, fmt.Errorf("at least one calendar is required")
+	}
+	if len(query.Calendars) > constants.MaxFreeBusyCalendars {
+		return models.FreeBusyResult{}, fmt.Errorf("at most %d calendars can be queried at once", constants.MaxFreeBusyCalendars)
+	}
+	if query.From.IsZero() || query.To.IsZero() || !query.To.After(query.From) {
+		return models.FreeBusyResult{}, fmt.Errorf("from and to are required and from must be before to")
+	}
+	if query.TimeZone != "" {
+		if err := utils.ValidateTimeZone(query.TimeZone); err != nil {
+			return models.FreeBusyResult{}, err
+		}
 	}
 
-	return nil
+	calendars, err := s.adapter.FreeBusy(token, query)
+	if err != nil {
+		return models.FreeBusyResult{}, err
+	}
+
+	var all []models.TimeRange
+	for _, c := range calendars {
+		for _, b := range c.Busy {
+			// Only the requested window matters for the merged view.
+			if b.Start.Before(query.From) {
+				b.Start = query.From
+			}
+			if b.End.After(query.To) {
+				b.End = query.To
+			}
+			all = append(all, b)
+		}
+	}
+	busy := utils.MergeTimeRanges(all)
+
+	return models.FreeBusyResult{
+		Calendars: calendars,
+		Busy:      busy,
+		Free:      utils.FreeTimeRanges(busy, query.From, query.To),
+	}, nil
 }
 
-func (s *CalendarService) Update(token string, e models.EditEvent) error {
+// FindMeetingTimes looks up the attendees' free/busy time and ranks the slots
+// in which every required attendee can meet.
+func (s *CalendarService) FindMeetingTimes(token string, req models.FindMeetingTime) (models.MeetingTimes, error) {
+	if len(req.Attendees) == 0 {
+		return models.MeetingTimes{}, fmt.Errorf("at least one attendee is required")
+	}
+	if req.DurationMinutes <= 0 {
+		return models.MeetingTimes{}, fmt.Errorf("duration_minutes must be positive")
+	}
+	if req.To.Sub(req.From) > constants.MaxMeetingSearchDays*24*time.Hour {
+		return models.MeetingTimes{}, fmt.Errorf("search window cannot exceed %d days", constants.MaxMeetingSearchDays)
+	}
+
+	attendees := make([]scheduling.Attendee, 0, len(req.Attendees))
+	freeBusy := models.FreeBusyQuery{From: req.From, To: req.To}
+	for _, a := range req.Attendees {
+		attendee, err := toSchedulingAttendee(a)
+		if err != nil {
+			return models.MeetingTimes{}, err
+		}
+		attendees = append(attendees, attendee)
+		freeBusy.Calendars = append(freeBusy.Calendars, a.Email)
+	}
+
+	busy, err := s.FreeBusy(token, freeBusy)
+	if err != nil {
+		return models.MeetingTimes{}, err
+	}
+
+	result := models.MeetingTimes{Slots: []models.MeetingSlot{}}
+	for i := range attendees {
+		c := busy.Calendars[attendees[i].ID]
+		for _, b := range c.Busy {
+			attendees[i].Busy = append(attendees[i].Busy, scheduling.Interval{Start: b.Start, End: b.End})
+		}
+		if len(c.Errors) > 0 {
+			if result.Errors == nil {
+				result.Errors = map[string][]string{}
+			}
+			result.Errors[attendees[i].ID] = c.Errors
+		}
+	}
+
+	slots, err := scheduling.FindSlots(scheduling.Request{
+		Attendees:  attendees,
+		Duration:   time.Duration(req.DurationMinutes) * time.Minute,
+		Window:     scheduling.Interval{Start: req.From, End: req.To},
+		Step:       time.Duration(req.StepMinutes) * time.Minute,
+		Buffer:     time.Duration(req.BufferMinutes) * time.Minute,
+		MaxResults: req.MaxResults,
+	})
+	if err != nil {
+		return models.MeetingTimes{}, err
+	}
+
+	for _, slot := range slots {
+		result.Slots = append(result.Slots, models.MeetingSlot{
+			Start:             slot.Start,
+			End:               slot.End,
+			OptionalConflicts: slot.OptionalConflicts,
+			BufferConflicts:   slot.BufferConflicts,
+		})
+	}
+	return result, nil
+}
+
+func toSchedulingAttendee(a models.MeetingAttendee) (scheduling.Attendee, error) {
+	if a.Email == "" {
+		return scheduling.Attendee{}, fmt.Errorf("attendee email is required")
+	}
+
+	// Working hours only mean something in a known zone; without them the
+	// zone is not used.
+	if a.WorkingHours != nil && a.TimeZone == "" {
+		return scheduling.Attendee{}, fmt.Errorf("%w: %s", ErrAttendeeTimeZone, a.Email)
+	}
+	attendee := scheduling.Attendee{ID: a.Email, Optional: a.Optional}
+	if a.TimeZone != "" {
+		if err := utils.ValidateTimeZone(a.TimeZone); err != nil {
+			return scheduling.Attendee{}, err
+		}
+		attendee.Location, _ = time.LoadLocation(a.TimeZone)
+	}
+	if a.WorkingHours == nil {
+		return attendee, nil
+	}
+
+	start, err := scheduling.ParseClock(a.WorkingHours.Start)
+	if err != nil {
+		return scheduling.Attendee{}, err
+	}
+	end, err := scheduling.ParseClock(a.WorkingHours.End)
+	if err != nil {
+		return scheduling.Attendee{}, err
+	}
+	hours := &scheduling.WorkingHours{Start: start, End: end}
+	for _, name := range a.WorkingHours.Days {
+		day, err := scheduling.ParseWeekday(name)
+		if err != nil {
+			return scheduling.Attendee{}, err
+		}
+		hours.Days = append(hours.Days, day)
+	}
+	attendee.WorkingHours = hours
+	return attendee, nil
+}
+
+// Update replaces an event. The overlapping events are returned for the
+// "warn" and "reject" conflict policies; rejected edits fail with
+// ErrEventConflict.
+func (s *CalendarService) Update(token string, e models.EditEvent) ([]models.Event, error) {
 	if e.ID == "" {
-		return fmt.Errorf("event ID is required")
+		return nil, fmt.Errorf("event ID is required")
 	}
 
 	if e.StartTime.IsZero() || e.EndTime.IsZero() {
-		return fmt.Errorf("start time and end time are required")
+		return nil, fmt.Errorf("start time and end time are required")
+	}
+
+	if err := validateEvent(e.TimeZone, e.Recurrence, e.ConflictPolicy); err != nil {
+		return nil, err
 	}
+	if err := validateSendUpdates(e.SendUpdates); err != nil {
+		return nil, err
+	}
+	if err := validateReminders(e.Reminders); err != nil {
+		return nil, err
+	}
+	opts := models.WriteOptions{SendUpdates: e.SendUpdates}
 
 	event := models.CreateEvent{
 		Summary:     e.Summary,
@@ -64,15 +411,496 @@ func (s *CalendarService) Update(token string, e models.EditEvent) error {
 		Location:    e.Location,
 		StartTime:   e.StartTime,
 		EndTime:     e.EndTime,
+		AllDay:      e.AllDay,
+		TimeZone:    e.TimeZone,
 		Attendees:   e.Attendees,
+		Recurrence:  e.Recurrence,
+		Reminders:   e.Reminders,
+	}
+	event.CreateConference = e.CreateConference
+
+	eventID := e.ID
+	calendarID := calendarOrPrimary(e.CalendarID)
+
+	switch e.Scope {
+	case "", constants.RecurrenceScopeThis:
+		opts.IfMatch = e.ETag
+	case constants.RecurrenceScopeFollowing:
+		if err := s.checkETag(token, calendarID, e.ID, e.ETag); err != nil {
+			return nil, err
+		}
+	case constants.RecurrenceScopeAll:
+		if err := s.checkETag(token, calendarID, e.ID, e.ETag); err != nil {
+			return nil, err
+		}
+		master, inst, err := s.seriesOf(token, calendarID, e.ID)
+		if err != nil {
+			return nil, err
+		}
+		// The times were edited on one occurrence; the series keeps its
+		// first date and moves by the same amount.
+		event.StartTime, event.EndTime, err = shiftToMaster(master, inst, e.StartTime, e.EndTime, e.AllDay)
+		if err != nil {
+			return nil, err
+		}
+		if len(event.Recurrence) == 0 {
+			event.Recurrence = master.Recurrence
+		}
+		if event.TimeZone == "" {
+			event.TimeZone = master.Start.TimeZone
+		}
+		eventID = master.Id
+	default:
+		return nil, fmt.Errorf("invalid scope %q", e.Scope)
 	}
 
 	ev := utils.AdjustEvent(event)
-	ev.Id = e.ID
+	ev.Id = eventID
+
+	conflicts, err := s.checkConflicts(token, calendarID, ev, e.ConflictPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicts) > 0 && e.ConflictPolicy == constants.ConflictPolicyReject {
+		return conflicts, ErrEventConflict
+	}
 
-	return s.adapter.UpdateEvent(token, *ev)
+	if e.Scope == constants.RecurrenceScopeFollowing {
+		return conflicts, s.splitSeries(token, calendarID, e.ID, ev, opts)
+	}
+	err = s.adapter.UpdateEvent(token, calendarID, *ev, opts)
+	return conflicts, s.staleEvent(token, calendarID, ev.Id, err)
 }
 
-func (s *CalendarService) Delete(token, eventID string) error {
-	return s.adapter.DeleteEvent(token, eventID)
+// Patch changes only the fields present in p. Overlaps are checked against
+// the patched event as in Update.
+func (s *CalendarService) Patch(token, eventID string, p models.PatchEvent) ([]models.Event, error) {
+	if eventID == "" {
+		return nil, fmt.Errorf("event ID is required")
+	}
+
+	timeZone := ""
+	if p.TimeZone != nil {
+		timeZone = *p.TimeZone
+	}
+	var recurrence []string
+	if p.Recurrence != nil {
+		recurrence = *p.Recurrence
+	}
+	if err := validateEvent(timeZone, recurrence, p.ConflictPolicy); err != nil {
+		return nil, err
+	}
+	if err := validateSendUpdates(p.SendUpdates); err != nil {
+		return nil, err
+	}
+	if err := validateReminders(p.Reminders); err != nil {
+		return nil, err
+	}
+	opts := models.WriteOptions{SendUpdates: p.SendUpdates}
+	calendarID := calendarOrPrimary(p.CalendarID)
+
+	var existing *calendar.Event
+	switch p.Scope {
+	case "", constants.RecurrenceScopeThis:
+		opts.IfMatch = p.ETag
+	case constants.RecurrenceScopeFollowing:
+		if err := s.checkETag(token, calendarID, eventID, p.ETag); err != nil {
+			return nil, err
+		}
+	case constants.RecurrenceScopeAll:
+		if err := s.checkETag(token, calendarID, eventID, p.ETag); err != nil {
+			return nil, err
+		}
+		master, inst, err := s.seriesOf(token, calendarID, eventID)
+		if err != nil {
+			return nil, err
+		}
+		if p.StartTime != nil || p.EndTime != nil {
+			// The new times are for the instance; the master moves by the
+			// same offset.
+			moved := utils.PatchToEvent(inst, p)
+			start, end, err := shiftToMaster(master, inst,
+				utils.ParseDateTime(moved.Start), utils.ParseDateTime(moved.End), moved.Start.Date != "")
+			if err != nil {
+				return nil, err
+			}
+			p.StartTime, p.EndTime = &start, &end
+		}
+		existing = master
+	default:
+		return nil, fmt.Errorf("invalid scope %q", p.Scope)
+	}
+
+	if existing == nil {
+		var err error
+		existing, err = s.adapter.GetEvent(token, calendarID, eventID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	targetID := existing.Id
+	patch := utils.PatchToEvent(existing, p)
+	merged, err := utils.MergeEvent(existing, patch)
+	if err != nil {
+		return nil, err
+	}
+	if !utils.ParseDateTime(merged.End).After(utils.ParseDateTime(merged.Start)) {
+		return nil, fmt.Errorf("end time must be after start time")
+	}
+
+	var conflicts []models.Event
+	if patch.Start != nil {
+		conflicts, err = s.checkConflicts(token, calendarID, merged, p.ConflictPolicy)
+		if err != nil {
+			return nil, err
+		}
+		if len(conflicts) > 0 && p.ConflictPolicy == constants.ConflictPolicyReject {
+			return conflicts, ErrEventConflict
+		}
+	}
+
+	if p.Scope == constants.RecurrenceScopeFollowing {
+		// The remaining occurrences become a new series built from the
+		// patched instance.
+		merged.RecurringEventId = ""
+		merged.OriginalStartTime = nil
+		merged.Recurrence = recurrence
+		return conflicts, s.splitSeries(token, calendarID, eventID, merged, opts)
+	}
+	err = s.adapter.PatchEvent(token, calendarID, targetID, patch, opts)
+	return conflicts, s.staleEvent(token, calendarID, targetID, err)
+}
+
+// Move moves an event to another calendar. Google cannot move a single
+// instance of a recurring event, so instances are only accepted with scope
+// "all" and then move their whole series.
+func (s *CalendarService) Move(token, eventID string, m models.MoveEvent) (models.Event, error) {
+	if eventID == "" {
+		return models.Event{}, fmt.Errorf("event ID is required")
+	}
+	if m.DestinationCalendarID == "" {
+		return models.Event{}, fmt.Errorf("destination calendar ID is required")
+	}
+	if err := validateSendUpdates(m.SendUpdates); err != nil {
+		return models.Event{}, err
+	}
+	switch m.Scope {
+	case "", constants.RecurrenceScopeAll:
+	default:
+		return models.Event{}, fmt.Errorf("invalid scope %q", m.Scope)
+	}
+	calendarID := calendarOrPrimary(m.SourceCalendarID)
+	if calendarID == m.DestinationCalendarID {
+		return models.Event{}, fmt.Errorf("source and destination calendars are the same")
+	}
+
+	masterID, err := s.seriesMasterID(token, calendarID, eventID)
+	if err != nil {
+		return models.Event{}, err
+	}
+	if masterID != eventID && m.Scope != constants.RecurrenceScopeAll {
+		return models.Event{}, ErrMoveInstance
+	}
+	moved, err := s.adapter.MoveEvent(token, calendarID, masterID, m.DestinationCalendarID, models.WriteOptions{SendUpdates: m.SendUpdates})
+	if err != nil {
+		return models.Event{}, err
+	}
+	return utils.ToEvent(moved), nil
+}
+
+func (s *CalendarService) Delete(token, eventID string, d models.DeleteEvent) error {
+	if err := validateSendUpdates(d.SendUpdates); err != nil {
+		return err
+	}
+	calendarID := calendarOrPrimary(d.CalendarID)
+	opts := models.WriteOptions{SendUpdates: d.SendUpdates}
+
+	switch d.Scope {
+	case "", constants.RecurrenceScopeThis:
+		opts.IfMatch = d.ETag
+		err := s.adapter.DeleteEvent(token, calendarID, eventID, opts)
+		return s.staleEvent(token, calendarID, eventID, err)
+	case constants.RecurrenceScopeAll:
+		if err := s.checkETag(token, calendarID, eventID, d.ETag); err != nil {
+			return err
+		}
+		masterID, err := s.seriesMasterID(token, calendarID, eventID)
+		if err != nil {
+			return err
+		}
+		return s.adapter.DeleteEvent(token, calendarID, masterID, opts)
+	case constants.RecurrenceScopeFollowing:
+		if err := s.checkETag(token, calendarID, eventID, d.ETag); err != nil {
+			return err
+		}
+		return s.splitSeries(token, calendarID, eventID, nil, opts)
+	default:
+		return fmt.Errorf("invalid scope %q", d.Scope)
+	}
+}
+
+// GetEvent returns one event or series instance, with its times rendered in
+// timeZone when one is given.
+func (s *CalendarService) GetEvent(token, calendarID, eventID, timeZone string) (models.Event, error) {
+	var loc *time.Location
+	if timeZone != "" {
+		if err := utils.ValidateTimeZone(timeZone); err != nil {
+			return models.Event{}, err
+		}
+		loc, _ = time.LoadLocation(timeZone)
+	}
+
+	ev, err := s.adapter.GetEvent(token, calendarOrPrimary(calendarID), eventID)
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	events := []models.Event{utils.ToEvent(ev)}
+	if loc != nil {
+		utils.EventsInTimeZone(events, loc)
+	}
+	return events[0], nil
+}
+
+// Respond records the signed-in user's answer to an invitation. The user is
+// found through the attendee's self flag or, for providers that do not set
+// it, by email.
+func (s *CalendarService) Respond(token, userEmail, eventID string, rsvp models.RSVP) error {
+	switch rsvp.Response {
+	case constants.ResponseAccepted, constants.ResponseDeclined, constants.ResponseTentative:
+	default:
+		return fmt.Errorf("invalid response %q", rsvp.Response)
+	}
+
+	calendarID := calendarOrPrimary(rsvp.CalendarID)
+	err := s.respond(token, userEmail, calendarID, eventID, rsvp)
+	// The ETag was fetched here rather than sent by the client, so an edit
+	// that slipped in between is not the client's conflict: the answer is
+	// applied once more to the new version.
+	if utils.HasStatusCode(err, http.StatusPreconditionFailed) {
+		err = s.respond(token, userEmail, calendarID, eventID, rsvp)
+	}
+	return s.staleEvent(token, calendarID, eventID, err)
+}
+
+// respond reads the event, sets the user's answer and writes the attendee
+// list back.
+func (s *CalendarService) respond(token, userEmail, calendarID, eventID string, rsvp models.RSVP) error {
+	ev, err := s.adapter.GetEvent(token, calendarID, eventID)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, a := range ev.Attendees {
+		if a.Self || (userEmail != "" && strings.EqualFold(a.Email, userEmail)) {
+			a.ResponseStatus = rsvp.Response
+			a.Comment = rsvp.Comment
+			found = true
+		}
+	}
+	if !found {
+		return ErrNotInvited
+	}
+
+	// Only the attendee list is sent, and only if nobody changed the event
+	// in between, so the answer cannot overwrite concurrent edits.
+	patch := &calendar.Event{Attendees: ev.Attendees}
+	return s.adapter.PatchEvent(token, calendarID, eventID, patch, models.WriteOptions{IfMatch: ev.Etag})
+}
+
+// seriesMasterID returns the ID of the series an instance belongs to, or the
+// ID itself for masters and single events.
+func (s *CalendarService) seriesMasterID(token, calendarID, eventID string) (string, error) {
+	ev, err := s.adapter.GetEvent(token, calendarID, eventID)
+	if err != nil {
+		return "", err
+	}
+	if ev.RecurringEventId != "" {
+		return ev.RecurringEventId, nil
+	}
+	return ev.Id, nil
+}
+
+// seriesOf returns the series master of eventID together with the event
+// itself. For masters and single events both are the same event.
+func (s *CalendarService) seriesOf(token, calendarID, eventID string) (master, inst *calendar.Event, err error) {
+	inst, err = s.adapter.GetEvent(token, calendarID, eventID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if inst.RecurringEventId == "" {
+		return inst, inst, nil
+	}
+	master, err = s.adapter.GetEvent(token, calendarID, inst.RecurringEventId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return master, inst, nil
+}
+
+// shiftToMaster turns new times given for inst into times for its series
+// master: the master moves by the same offset, so editing any occurrence
+// keeps the series' first date. All-day series move by whole days.
+func shiftToMaster(master, inst *calendar.Event, start, end time.Time, allDay bool) (time.Time, time.Time, error) {
+	if allDay != (inst.Start.Date != "") {
+		return time.Time{}, time.Time{}, fmt.Errorf("all_day cannot be changed for a whole series")
+	}
+
+	if allDay {
+		startDate, endDate := utils.AllDayRange(start, end)
+		newStart, _ := time.Parse("2006-01-02", startDate)
+		newEnd, _ := time.Parse("2006-01-02", endDate)
+		startShift := newStart.Sub(utils.ParseDateTime(inst.Start))
+		endShift := newEnd.Sub(utils.ParseDateTime(inst.End))
+		return utils.ParseDateTime(master.Start).Add(startShift), utils.ParseDateTime(master.End).Add(endShift), nil
+	}
+
+	startShift := start.Sub(utils.ParseDateTime(inst.Start))
+	endShift := end.Sub(utils.ParseDateTime(inst.End))
+	return utils.ParseDateTime(master.Start).Add(startShift), utils.ParseDateTime(master.End).Add(endShift), nil
+}
+
+// splitSeries ends the series of instanceID just before that instance. When
+// replacement is set it becomes a new series covering the remaining
+// occurrences; otherwise those occurrences are cancelled.
+func (s *CalendarService) splitSeries(token, calendarID, instanceID string, replacement *calendar.Event, opts models.WriteOptions) error {
+	inst, err := s.adapter.GetEvent(token, calendarID, instanceID)
+	if err != nil {
+		return err
+	}
+	if inst.RecurringEventId == "" {
+		return fmt.Errorf("event %s is not an instance of a recurring event", instanceID)
+	}
+
+	master, err := s.adapter.GetEvent(token, calendarID, inst.RecurringEventId)
+	if err != nil {
+		return err
+	}
+
+	seriesStart := utils.ParseSeriesStart(master.Start)
+	splitAt := utils.ParseDateTime(inst.OriginalStartTime)
+
+	// Splitting at the first instance affects the whole series.
+	if !splitAt.After(seriesStart) {
+		if replacement == nil {
+			return s.adapter.DeleteEvent(token, calendarID, master.Id, opts)
+		}
+		if len(replacement.Recurrence) == 0 {
+			replacement.Recurrence = master.Recurrence
+		}
+		replacement.Id = master.Id
+		return s.adapter.UpdateEvent(token, calendarID, *replacement, opts)
+	}
+
+	head, tail, err := utils.SplitRecurrence(master.Recurrence, seriesStart, splitAt, master.Start.Date != "")
+	if err != nil {
+		return err
+	}
+
+	master.Recurrence = head
+	if err := s.adapter.UpdateEvent(token, calendarID, *master, opts); err != nil {
+		return err
+	}
+
+	if replacement == nil {
+		return nil
+	}
+	if len(replacement.Recurrence) == 0 {
+		replacement.Recurrence = tail
+	}
+	replacement.Id = ""
+	_, err = s.adapter.CreateEvent(token, calendarID, replacement, opts)
+	return err
+}
+
+func calendarOrPrimary(calendarID string) string {
+	if calendarID == "" {
+		return constants.PrimaryCalendarID
+	}
+	return calendarID
+}
+
+func validateACLRole(role string) error {
+	switch role {
+	case constants.ACLRoleFreeBusyReader, constants.ACLRoleReader, constants.ACLRoleWriter, constants.ACLRoleOwner:
+		return nil
+	default:
+		return fmt.Errorf("invalid role %q", role)
+	}
+}
+
+func validateEvent(timeZone string, recurrence []string, conflictPolicy string) error {
+	switch conflictPolicy {
+	case "", constants.ConflictPolicyAllow, constants.ConflictPolicyWarn, constants.ConflictPolicyReject:
+	default:
+		return fmt.Errorf("invalid conflict policy %q", conflictPolicy)
+	}
+	if timeZone != "" {
+		if err := utils.ValidateTimeZone(timeZone); err != nil {
+			return err
+		}
+	}
+	return utils.ValidateRecurrence(recurrence)
+}
+
+func validateEventQuery(query models.EventQuery) error {
+	switch query.Status {
+	case "", constants.EventStatusConfirmed, constants.EventStatusTentative, constants.EventStatusCancelled:
+	default:
+		return fmt.Errorf("invalid status %q", query.Status)
+	}
+	switch query.EventType {
+	case "", constants.EventTypeDefault, constants.EventTypeBirthday, constants.EventTypeFocusTime,
+		constants.EventTypeFromGmail, constants.EventTypeOutOfOffice, constants.EventTypeWorkingLocation:
+	default:
+		return fmt.Errorf("invalid event_type %q", query.EventType)
+	}
+	switch query.OrderBy {
+	case "", constants.OrderByUpdated:
+	case constants.OrderByStartTime:
+		if !query.SingleEvents {
+			return fmt.Errorf("order_by startTime requires single_events")
+		}
+	default:
+		return fmt.Errorf("invalid order_by %q", query.OrderBy)
+	}
+	if query.UpdatedMin != "" {
+		if _, err := time.Parse(time.RFC3339, query.UpdatedMin); err != nil {
+			return fmt.Errorf("updated_min must be an RFC3339 time")
+		}
+	}
+	return nil
+}
+
+func validateReminders(r *models.Reminders) error {
+	if r == nil {
+		return nil
+	}
+	if r.UseDefault && len(r.Overrides) > 0 {
+		return fmt.Errorf("reminder overrides cannot be combined with use_default")
+	}
+	if len(r.Overrides) > constants.MaxReminderOverrides {
+		return fmt.Errorf("at most %d reminder overrides are allowed", constants.MaxReminderOverrides)
+	}
+	for _, o := range r.Overrides {
+		switch o.Method {
+		case constants.ReminderMethodPopup, constants.ReminderMethodEmail:
+		default:
+			return fmt.Errorf("invalid reminder method %q", o.Method)
+		}
+		if o.Minutes < 0 || o.Minutes > constants.MaxReminderMinutes {
+			return fmt.Errorf("reminder minutes must be between 0 and %d", constants.MaxReminderMinutes)
+		}
+	}
+	return nil
+}
+
+func validateSendUpdates(sendUpdates string) error {
+	switch sendUpdates {
+	case "", constants.SendUpdatesAll, constants.SendUpdatesExternalOnly, constants.SendUpdatesNone:
+		return nil
+	default:
+		return fmt.Errorf("invalid send_updates %q", sendUpdates)
+	}
 }
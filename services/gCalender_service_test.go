@@ -0,0 +1,285 @@
+package services
+
+import (
+	"backend/adapters"
+	"backend/constants"
+	"backend/models"
+	"backend/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+func newMemoryService() *CalendarService {
+	return NewCalendarService(adapters.NewMemoryAdapter())
+}
+
+func TestACLRuleLifecycle(t *testing.T) {
+	s := newMemoryService()
+	cal := constants.PrimaryCalendarID
+
+	rules, err := s.ListACLRules("token", cal)
+	if err != nil || len(rules) != 0 {
+		t.Fatalf("ListACLRules on a new calendar = %v, %v", rules, err)
+	}
+
+	granted, err := s.CreateACLRule("token", cal, models.CreateACLRule{
+		Role:       constants.ACLRoleReader,
+		ScopeType:  constants.ACLScopeUser,
+		ScopeValue: "alice@example.com",
+	})
+	if err != nil {
+		t.Fatalf("CreateACLRule: %v", err)
+	}
+	want := models.ACLRule{
+		ID:         "user:alice@example.com",
+		Role:       constants.ACLRoleReader,
+		ScopeType:  constants.ACLScopeUser,
+		ScopeValue: "alice@example.com",
+	}
+	if granted != want {
+		t.Errorf("CreateACLRule = %+v, want %+v", granted, want)
+	}
+	if _, err := s.CreateACLRule("token", cal, models.CreateACLRule{
+		Role:       constants.ACLRoleFreeBusyReader,
+		ScopeType:  constants.ACLScopeDomain,
+		ScopeValue: "example.com",
+	}); err != nil {
+		t.Fatalf("CreateACLRule for a domain: %v", err)
+	}
+
+	changed, err := s.UpdateACLRule("token", cal, granted.ID, models.EditACLRule{Role: constants.ACLRoleWriter})
+	if err != nil {
+		t.Fatalf("UpdateACLRule: %v", err)
+	}
+	if changed.Role != constants.ACLRoleWriter || changed.ScopeValue != "alice@example.com" {
+		t.Errorf("UpdateACLRule = %+v", changed)
+	}
+
+	rules, err = s.ListACLRules("token", cal)
+	if err != nil {
+		t.Fatalf("ListACLRules: %v", err)
+	}
+	var ids, roles []string
+	for _, r := range rules {
+		ids = append(ids, r.ID)
+		roles = append(roles, r.Role)
+	}
+	if want := []string{"domain:example.com", "user:alice@example.com"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("rule IDs = %v, want %v", ids, want)
+	}
+	if want := []string{constants.ACLRoleFreeBusyReader, constants.ACLRoleWriter}; !reflect.DeepEqual(roles, want) {
+		t.Errorf("rule roles = %v, want %v", roles, want)
+	}
+
+	if err := s.DeleteACLRule("token", cal, granted.ID); err != nil {
+		t.Fatalf("DeleteACLRule: %v", err)
+	}
+	rules, _ = s.ListACLRules("token", cal)
+	if len(rules) != 1 || rules[0].ID != "domain:example.com" {
+		t.Errorf("rules after revoke = %+v", rules)
+	}
+
+	if err := s.DeleteACLRule("token", cal, granted.ID); !utils.HasStatusCode(err, http.StatusNotFound) {
+		t.Errorf("revoking twice = %v, want 404", err)
+	}
+	if _, err := s.UpdateACLRule("token", cal, "user:nobody@example.com", models.EditACLRule{Role: constants.ACLRoleReader}); !utils.HasStatusCode(err, http.StatusNotFound) {
+		t.Errorf("changing an unknown rule = %v, want 404", err)
+	}
+	if _, err := s.ListACLRules("token", "missing"); !utils.HasStatusCode(err, http.StatusNotFound) {
+		t.Errorf("listing an unknown calendar = %v, want 404", err)
+	}
+}
+
+func TestACLRuleRegrantUpdatesRole(t *testing.T) {
+	s := newMemoryService()
+	cal := constants.PrimaryCalendarID
+	rule := models.CreateACLRule{Role: constants.ACLRoleReader, ScopeType: constants.ACLScopeGroup, ScopeValue: "team@example.com"}
+
+	if _, err := s.CreateACLRule("token", cal, rule); err != nil {
+		t.Fatalf("CreateACLRule: %v", err)
+	}
+	rule.Role = constants.ACLRoleOwner
+	if _, err := s.CreateACLRule("token", cal, rule); err != nil {
+		t.Fatalf("CreateACLRule again: %v", err)
+	}
+
+	rules, _ := s.ListACLRules("token", cal)
+	if len(rules) != 1 || rules[0].Role != constants.ACLRoleOwner {
+		t.Errorf("rules = %+v, want one owner rule", rules)
+	}
+}
+
+func TestCreateACLRuleValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		rule models.CreateACLRule
+	}{
+		{"unknown role", models.CreateACLRule{Role: "admin", ScopeType: constants.ACLScopeUser, ScopeValue: "a@example.com"}},
+		{"empty role", models.CreateACLRule{ScopeType: constants.ACLScopeUser, ScopeValue: "a@example.com"}},
+		{"unknown scope", models.CreateACLRule{Role: constants.ACLRoleReader, ScopeType: "everyone", ScopeValue: "a@example.com"}},
+		{"default scope", models.CreateACLRule{Role: constants.ACLRoleReader, ScopeType: "default"}},
+		{"user without email", models.CreateACLRule{Role: constants.ACLRoleReader, ScopeType: constants.ACLScopeUser, ScopeValue: "alice"}},
+		{"group without email", models.CreateACLRule{Role: constants.ACLRoleReader, ScopeType: constants.ACLScopeGroup}},
+		{"domain with email", models.CreateACLRule{Role: constants.ACLRoleReader, ScopeType: constants.ACLScopeDomain, ScopeValue: "a@example.com"}},
+		{"empty domain", models.CreateACLRule{Role: constants.ACLRoleReader, ScopeType: constants.ACLScopeDomain}},
+	}
+
+	s := newMemoryService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.CreateACLRule("token", constants.PrimaryCalendarID, tt.rule); err == nil {
+				t.Error("CreateACLRule succeeded, want a validation error")
+			}
+		})
+	}
+
+	rules, _ := s.ListACLRules("token", constants.PrimaryCalendarID)
+	if len(rules) != 0 {
+		t.Errorf("invalid grants were stored: %+v", rules)
+	}
+}
+
+func TestUpdateACLRuleValidatesRole(t *testing.T) {
+	s := newMemoryService()
+	granted, err := s.CreateACLRule("token", constants.PrimaryCalendarID, models.CreateACLRule{
+		Role:       constants.ACLRoleReader,
+		ScopeType:  constants.ACLScopeUser,
+		ScopeValue: "alice@example.com",
+	})
+	if err != nil {
+		t.Fatalf("CreateACLRule: %v", err)
+	}
+
+	for _, role := range []string{"", "none", "Reader"} {
+		if _, err := s.UpdateACLRule("token", constants.PrimaryCalendarID, granted.ID, models.EditACLRule{Role: role}); err == nil {
+			t.Errorf("UpdateACLRule with role %q succeeded", role)
+		}
+	}
+	rules, _ := s.ListACLRules("token", constants.PrimaryCalendarID)
+	if len(rules) != 1 || rules[0].Role != constants.ACLRoleReader {
+		t.Errorf("rules = %+v, want the reader grant unchanged", rules)
+	}
+}
+
+func TestFindMeetingTimesAttendeeTimeZone(t *testing.T) {
+	hours := &models.WorkingHours{Start: "09:00", End: "17:00"}
+	from := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC) // a Monday
+
+	tests := []struct {
+		name      string
+		attendee  models.MeetingAttendee
+		wantErr   error
+		wantFirst time.Time
+	}{
+		{
+			name:     "working hours without a zone",
+			attendee: models.MeetingAttendee{Email: "a@example.com", WorkingHours: hours},
+			wantErr:  ErrAttendeeTimeZone,
+		},
+		{
+			name:      "working hours in their zone",
+			attendee:  models.MeetingAttendee{Email: "a@example.com", TimeZone: "America/New_York", WorkingHours: hours},
+			wantFirst: time.Date(2030, 1, 7, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "no working hours needs no zone",
+			attendee:  models.MeetingAttendee{Email: "a@example.com"},
+			wantFirst: from,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := newMemoryService().FindMeetingTimes("token", models.FindMeetingTime{
+				Attendees:       []models.MeetingAttendee{tt.attendee},
+				DurationMinutes: 30,
+				From:            from,
+				To:              from.Add(24 * time.Hour),
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("FindMeetingTimes error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindMeetingTimes: %v", err)
+			}
+			if len(result.Slots) == 0 || !result.Slots[0].Start.Equal(tt.wantFirst) {
+				t.Errorf("slots = %+v, want the first at %s", result.Slots, tt.wantFirst)
+			}
+		})
+	}
+}
+
+// racingAdapter edits the event behind the caller's back right before the
+// first n patches, as another client would.
+type racingAdapter struct {
+	adapters.CalendarProvider
+	races int
+}
+
+func (a *racingAdapter) PatchEvent(token, calendarID, eventID string, patch *calendar.Event, opts models.WriteOptions) error {
+	if a.races > 0 {
+		a.races--
+		edit := &calendar.Event{Location: fmt.Sprintf("Room %d", a.races)}
+		if err := a.CalendarProvider.PatchEvent(token, calendarID, eventID, edit, models.WriteOptions{}); err != nil {
+			return err
+		}
+	}
+	return a.CalendarProvider.PatchEvent(token, calendarID, eventID, patch, opts)
+}
+
+func TestRespondRetriesAfterConcurrentEdit(t *testing.T) {
+	tests := []struct {
+		name      string
+		races     int
+		wantStale bool
+	}{
+		{"no concurrent edit", 0, false},
+		{"one concurrent edit", 1, false},
+		{"edited on every attempt", 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := adapters.NewMemoryAdapter()
+			ev, err := memory.CreateEvent("token", constants.PrimaryCalendarID, &calendar.Event{
+				Summary:   "Review",
+				Start:     &calendar.EventDateTime{DateTime: "2030-01-07T09:00:00Z"},
+				End:       &calendar.EventDateTime{DateTime: "2030-01-07T10:00:00Z"},
+				Attendees: []*calendar.EventAttendee{{Email: "me@example.com", ResponseStatus: "needsAction"}},
+			}, models.WriteOptions{})
+			if err != nil {
+				t.Fatalf("CreateEvent: %v", err)
+			}
+			s := NewCalendarService(&racingAdapter{CalendarProvider: memory, races: tt.races})
+
+			err = s.Respond("token", "me@example.com", ev.Id, models.RSVP{Response: constants.ResponseAccepted})
+			var stale *StaleEventError
+			if tt.wantStale {
+				if !errors.As(err, &stale) {
+					t.Fatalf("Respond = %v, want a StaleEventError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Respond: %v", err)
+			}
+
+			got, _ := memory.GetEvent("token", constants.PrimaryCalendarID, ev.Id)
+			if status := got.Attendees[0].ResponseStatus; status != constants.ResponseAccepted {
+				t.Errorf("response status = %q, want %q", status, constants.ResponseAccepted)
+			}
+			if tt.races > 0 && got.Location != "Room 0" {
+				t.Errorf("location = %q, the concurrent edit was lost", got.Location)
+			}
+		})
+	}
+}
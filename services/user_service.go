@@ -25,6 +25,7 @@ type AuthService interface {
 	GetUserInfo(accessToken string) (*dtos.GoogleUserInfo, error)
 	ProcessGoogleUser(userInfo *dtos.GoogleUserInfo, token *oauth2.Token, jwtToken string) (*models.User, error)
 	GenerateJWT(user *models.User) (string, time.Time, error)
+	UpdateTimeZone(googleID, timeZone string) (*models.User, error)
 }
 
 type authService struct {
@@ -149,6 +150,23 @@ func (s *authService) GenerateJWT(user *models.User) (string, time.Time, error)
 	return tokenString, expTime, nil
 }
 
+func (s *authService) UpdateTimeZone(googleID, timeZone string) (*models.User, error) {
+	if err := utils.ValidateTimeZone(timeZone); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByGoogleID(googleID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.TimeZone = timeZone
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
 func (s *authService) CreateFolderId(googleId, jwtToken string) (string, error) {
 	folderInfoUrl := "http://localhost:8081/storage/folder"
 	payload := map[string]interface{}{"title": googleId}
@@ -1,49 +1,480 @@
 package utils
 
 import (
+	"backend/constants"
 	"backend/models"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"golang.org/x/oauth2"
 	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
 )
 
+const dateLayout = "2006-01-02"
+
 func AdjustEvent(event models.CreateEvent) *calendar.Event {
-	attendees := []*calendar.EventAttendee{}
-	for _, a := range event.Attendees {
-		attendees = append(attendees, &calendar.EventAttendee{
-			Email: a.Email,
-		})
+	attendees := toEventAttendees(event.Attendees)
+
+	// Without a zone the times keep the client's offset and Google falls back
+	// to the calendar's time zone.
+	timeZone := event.TimeZone
+	startTime, endTime := event.StartTime, event.EndTime
+	if timeZone != "" {
+		if loc, err := time.LoadLocation(timeZone); err == nil {
+			startTime, endTime = startTime.In(loc), endTime.In(loc)
+		}
+	}
+
+	start := &calendar.EventDateTime{
+		DateTime: startTime.Format(time.RFC3339),
+		TimeZone: timeZone,
+	}
+	end := &calendar.EventDateTime{
+		DateTime: endTime.Format(time.RFC3339),
+		TimeZone: timeZone,
+	}
+	if event.AllDay {
+		startDate, endDate := AllDayRange(event.StartTime, event.EndTime)
+		start = &calendar.EventDateTime{Date: startDate}
+		end = &calendar.EventDateTime{Date: endDate}
 	}
 
-	return &calendar.Event{
+	ev := &calendar.Event{
 		Summary:     event.Summary,
 		Description: event.Description,
 		Location:    event.Location,
-		Start: &calendar.EventDateTime{
-			DateTime: event.StartTime.Format(time.RFC3339),
-			TimeZone: "Asia/Jakarta",
-		},
-		End: &calendar.EventDateTime{
-			DateTime: event.EndTime.Format(time.RFC3339),
-			TimeZone: "Asia/Jakarta",
+		Start:       start,
+		End:         end,
+		Attendees:   attendees,
+		Recurrence:  event.Recurrence,
+		Reminders:   toEventReminders(event.Reminders),
+	}
+	if event.CreateConference {
+		ev.ConferenceData = newConferenceRequest()
+	}
+	return ev
+}
+
+// PatchToEvent builds the body of a partial update of existing. Only the
+// fields present in p are sent; changed times are completed from existing,
+// whose time zone is kept unless p sets one.
+func PatchToEvent(existing *calendar.Event, p models.PatchEvent) *calendar.Event {
+	patch := &calendar.Event{}
+	if p.Summary != nil {
+		patch.Summary = *p.Summary
+		patch.ForceSendFields = append(patch.ForceSendFields, "Summary")
+	}
+	if p.Description != nil {
+		patch.Description = *p.Description
+		patch.ForceSendFields = append(patch.ForceSendFields, "Description")
+	}
+	if p.Location != nil {
+		patch.Location = *p.Location
+		patch.ForceSendFields = append(patch.ForceSendFields, "Location")
+	}
+	if p.Attendees != nil {
+		patch.Attendees = toEventAttendees(*p.Attendees)
+		patch.ForceSendFields = append(patch.ForceSendFields, "Attendees")
+	}
+	if p.Recurrence != nil {
+		patch.Recurrence = *p.Recurrence
+		patch.ForceSendFields = append(patch.ForceSendFields, "Recurrence")
+	}
+	if p.Reminders != nil {
+		patch.Reminders = toEventReminders(p.Reminders)
+	}
+	if p.CreateConference {
+		patch.ConferenceData = newConferenceRequest()
+	}
+
+	if p.StartTime != nil || p.EndTime != nil || p.AllDay != nil || p.TimeZone != nil {
+		times := models.CreateEvent{
+			StartTime: ParseDateTime(existing.Start),
+			EndTime:   ParseDateTime(existing.End),
+			AllDay:    existing.Start != nil && existing.Start.Date != "",
+		}
+		if existing.Start != nil {
+			times.TimeZone = existing.Start.TimeZone
+		}
+		if p.StartTime != nil {
+			// Moving only the start keeps the event's duration.
+			times.EndTime = p.StartTime.Add(times.EndTime.Sub(times.StartTime))
+			times.StartTime = *p.StartTime
+		}
+		if p.EndTime != nil {
+			times.EndTime = *p.EndTime
+		}
+		if p.AllDay != nil {
+			times.AllDay = *p.AllDay
+		}
+		if p.TimeZone != nil {
+			times.TimeZone = *p.TimeZone
+		}
+		adjusted := AdjustEvent(times)
+		patch.Start, patch.End = adjusted.Start, adjusted.End
+	}
+	return patch
+}
+
+// MergeEvent returns a copy of base with the fields sent by patch applied,
+// which is how the provider will store the patched event.
+func MergeEvent(base, patch *calendar.Event) (*calendar.Event, error) {
+	merged := &calendar.Event{}
+	data, err := json.Marshal(base)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, merged); err != nil {
+		return nil, err
+	}
+	if data, err = json.Marshal(patch); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, merged); err != nil {
+		return nil, err
+	}
+
+	// Nested objects are replaced, not merged, so that for example a timed
+	// start does not keep the date of an all-day one.
+	if patch.Start != nil {
+		merged.Start, merged.End = patch.Start, patch.End
+	}
+	if patch.Reminders != nil {
+		merged.Reminders = patch.Reminders
+	}
+	if patch.ConferenceData != nil {
+		merged.ConferenceData = patch.ConferenceData
+	}
+	return merged, nil
+}
+
+func toEventAttendees(list []models.Attendees) []*calendar.EventAttendee {
+	attendees := []*calendar.EventAttendee{}
+	for _, a := range list {
+		attendees = append(attendees, &calendar.EventAttendee{
+			Email:          a.Email,
+			DisplayName:    a.DisplayName,
+			Optional:       a.Optional,
+			ResponseStatus: a.ResponseStatus,
+		})
+	}
+	return attendees
+}
+
+func newConferenceRequest() *calendar.ConferenceData {
+	return &calendar.ConferenceData{
+		CreateRequest: &calendar.CreateConferenceRequest{
+			RequestId: newRequestID(),
+			ConferenceSolutionKey: &calendar.ConferenceSolutionKey{
+				Type: constants.ConferenceTypeMeet,
+			},
 		},
-		Attendees: attendees,
 	}
 }
 
+// newRequestID returns a random ID so that retried conference requests are
+// not mistaken for the previous one.
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func toEventReminders(r *models.Reminders) *calendar.EventReminders {
+	if r == nil {
+		return nil
+	}
+	reminders := &calendar.EventReminders{
+		UseDefault: r.UseDefault,
+		// Overrides only apply when useDefault is explicitly false.
+		ForceSendFields: []string{"UseDefault", "Overrides"},
+		Overrides:       []*calendar.EventReminder{},
+	}
+	for _, o := range r.Overrides {
+		reminders.Overrides = append(reminders.Overrides, &calendar.EventReminder{
+			Method:          o.Method,
+			Minutes:         o.Minutes,
+			ForceSendFields: []string{"Minutes"},
+		})
+	}
+	return reminders
+}
+
+// AllDayRange returns the start date and exclusive end date of an all-day
+// event. Dates are read from the wall clock of each time as sent by the
+// client, so they never shift across time zones. An end that is not at
+// midnight includes its own day, and the event always covers at least one day.
+func AllDayRange(startTime, endTime time.Time) (string, string) {
+	startDay := time.Date(startTime.Year(), startTime.Month(), startTime.Day(), 0, 0, 0, 0, time.UTC)
+	endDay := time.Date(endTime.Year(), endTime.Month(), endTime.Day(), 0, 0, 0, 0, time.UTC)
+
+	h, m, s := endTime.Clock()
+	if h != 0 || m != 0 || s != 0 || endTime.Nanosecond() != 0 {
+		endDay = endDay.AddDate(0, 0, 1)
+	}
+	if !endDay.After(startDay) {
+		endDay = startDay.AddDate(0, 0, 1)
+	}
+	return startDay.Format(dateLayout), endDay.Format(dateLayout)
+}
+
 func ParseDateTime(dt *calendar.EventDateTime) time.Time {
+	if dt == nil {
+		return time.Time{}
+	}
 	if dt.DateTime != "" {
 		t, _ := time.Parse(time.RFC3339, dt.DateTime)
 		return t
 	}
 
-	t, _ := time.Parse("2006-01-02", dt.Date)
+	t, _ := time.Parse(dateLayout, dt.Date)
 	return t
 }
 
+// ParseSeriesStart parses the start of a recurring series in its IANA time
+// zone, so that expanding the series follows the zone's DST changes instead
+// of the fixed offset the start was written with.
+func ParseSeriesStart(dt *calendar.EventDateTime) time.Time {
+	t := ParseDateTime(dt)
+	if dt == nil || dt.DateTime == "" || dt.TimeZone == "" {
+		return t
+	}
+	if loc, err := time.LoadLocation(dt.TimeZone); err == nil {
+		t = t.In(loc)
+	}
+	return t
+}
+
+// ToEvent converts a Google calendar event into the API representation.
+func ToEvent(e *calendar.Event) models.Event {
+	ev := models.Event{
+		ID:               e.Id,
+		ETag:             e.Etag,
+		Summary:          e.Summary,
+		Description:      e.Description,
+		Location:         e.Location,
+		StartTime:        ParseDateTime(e.Start),
+		EndTime:          ParseDateTime(e.End),
+		Status:           e.Status,
+		EventType:        e.EventType,
+		Transparency:     e.Transparency,
+		HTMLLink:         e.HtmlLink,
+		Recurrence:       e.Recurrence,
+		RecurringEventID: e.RecurringEventId,
+	}
+	if e.Start != nil && e.Start.Date != "" {
+		// All-day times are midnight UTC; the dates are the source of truth.
+		ev.AllDay = true
+		ev.StartDate = e.Start.Date
+		if e.End != nil {
+			ev.EndDate = e.End.Date
+		}
+	}
+	for _, a := range e.Attendees {
+		ev.Attendees = append(ev.Attendees, models.Attendees{
+			Email:          a.Email,
+			DisplayName:    a.DisplayName,
+			Optional:       a.Optional,
+			ResponseStatus: a.ResponseStatus,
+			Organizer:      a.Organizer,
+			Self:           a.Self,
+		})
+	}
+	if e.OriginalStartTime != nil {
+		original := ParseDateTime(e.OriginalStartTime)
+		ev.OriginalStartTime = &original
+	}
+	if c := e.ConferenceData; c != nil {
+		ev.Conference = &models.Conference{ID: c.ConferenceId}
+		if c.CreateRequest != nil && c.CreateRequest.Status != nil {
+			ev.Conference.Status = c.CreateRequest.Status.StatusCode
+		}
+		for _, p := range c.EntryPoints {
+			if p.EntryPointType == "video" && ev.Conference.JoinURL == "" {
+				ev.Conference.JoinURL = p.Uri
+			}
+			ev.Conference.EntryPoints = append(ev.Conference.EntryPoints, models.ConferenceEntryPoint{
+				Type:  p.EntryPointType,
+				URI:   p.Uri,
+				Label: p.Label,
+				PIN:   p.Pin,
+			})
+		}
+		if ev.Conference.JoinURL == "" {
+			ev.Conference.JoinURL = e.HangoutLink
+		}
+	}
+	if e.Creator != nil {
+		ev.Creator = &models.Person{Email: e.Creator.Email, DisplayName: e.Creator.DisplayName, Self: e.Creator.Self}
+	}
+	if e.Organizer != nil {
+		ev.Organizer = &models.Person{Email: e.Organizer.Email, DisplayName: e.Organizer.DisplayName, Self: e.Organizer.Self}
+	}
+	ev.Created = parseTimestamp(e.Created)
+	ev.Updated = parseTimestamp(e.Updated)
+	if e.Reminders != nil {
+		ev.Reminders = &models.Reminders{UseDefault: e.Reminders.UseDefault}
+		for _, o := range e.Reminders.Overrides {
+			ev.Reminders.Overrides = append(ev.Reminders.Overrides, models.Reminder{
+				Method:  o.Method,
+				Minutes: o.Minutes,
+			})
+		}
+	}
+	return ev
+}
+
+// ToCalendar converts a calendar list entry into the API representation.
+func ToCalendar(c *calendar.CalendarListEntry) models.Calendar {
+	return models.Calendar{
+		ID:          c.Id,
+		Summary:     c.Summary,
+		Description: c.Description,
+		Color:       c.BackgroundColor,
+		AccessRole:  c.AccessRole,
+		TimeZone:    c.TimeZone,
+		Primary:     c.Primary,
+	}
+}
+
+// ToOwnedCalendar converts a calendar created or edited by the user, which
+// the user always owns.
+func ToOwnedCalendar(c *calendar.Calendar) models.Calendar {
+	return models.Calendar{
+		ID:          c.Id,
+		Summary:     c.Summary,
+		Description: c.Description,
+		AccessRole:  "owner",
+		TimeZone:    c.TimeZone,
+	}
+}
+
+// ToACLRule converts a Google ACL rule into the API representation.
+func ToACLRule(r *calendar.AclRule) models.ACLRule {
+	rule := models.ACLRule{
+		ID:   r.Id,
+		Role: r.Role,
+	}
+	if r.Scope != nil {
+		rule.ScopeType = r.Scope.Type
+		rule.ScopeValue = r.Scope.Value
+	}
+	return rule
+}
+
+// HasStatusCode reports whether err is a Google API error with the given
+// HTTP status code.
+func HasStatusCode(err error, code int) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// SortEvents orders events by start time, keeping the order of equal starts.
+func SortEvents(events []models.Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].StartTime.Before(events[j].StartTime)
+	})
+}
+
+// SortEventsBy orders events by start time or, for "updated", by last
+// modification.
+func SortEventsBy(events []models.Event, orderBy string) {
+	if orderBy != constants.OrderByUpdated {
+		SortEvents(events)
+		return
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return updatedAt(events[i]).Before(updatedAt(events[j]))
+	})
+}
+
+// parseTimestamp parses an RFC3339 timestamp such as an event's created
+// time, returning nil when it is missing.
+func parseTimestamp(value string) *time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+func updatedAt(e models.Event) time.Time {
+	if e.Updated == nil {
+		return time.Time{}
+	}
+	return *e.Updated
+}
+
+// MatchesEventQuery reports whether e passes the search filters of query.
+// Time range and paging are left to the caller.
+func MatchesEventQuery(e models.Event, query models.EventQuery) bool {
+	if query.Q != "" && !matchesText(e, query.Q) {
+		return false
+	}
+	if query.AttendeeEmail != "" {
+		found := false
+		for _, a := range e.Attendees {
+			found = found || strings.EqualFold(a.Email, query.AttendeeEmail)
+		}
+		if !found {
+			return false
+		}
+	}
+	if query.Location != "" && !containsFold(e.Location, query.Location) {
+		return false
+	}
+	if query.Status != "" && e.Status != query.Status {
+		return false
+	}
+	if query.EventType != "" {
+		eventType := e.EventType
+		if eventType == "" {
+			eventType = constants.EventTypeDefault
+		}
+		if eventType != query.EventType {
+			return false
+		}
+	}
+	if query.UpdatedMin != "" {
+		updatedMin, err := time.Parse(time.RFC3339, query.UpdatedMin)
+		if err == nil && updatedAt(e).Before(updatedMin) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesText requires every word of q to appear in one of the searchable
+// fields, like Google's free-text search.
+func matchesText(e models.Event, q string) bool {
+	fields := []string{e.Summary, e.Description, e.Location}
+	for _, a := range e.Attendees {
+		fields = append(fields, a.Email, a.DisplayName)
+	}
+	for _, word := range strings.Fields(q) {
+		found := false
+		for _, f := range fields {
+			found = found || containsFold(f, word)
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
 func RefreshAccessToken(ctx context.Context, config *oauth2.Config, refreshToken string) (*oauth2.Token, error) {
 	tok := &oauth2.Token{
 		RefreshToken: refreshToken,
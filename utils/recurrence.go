@@ -0,0 +1,426 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	recurrenceUTCLayout      = "20060102T150405Z"
+	recurrenceLocalLayout    = "20060102T150405"
+	recurrenceDateLayout     = "20060102"
+	maxRecurrenceOccurrences = 5000
+)
+
+// ErrUnsupportedRecurrence is returned when a rule is valid but cannot be
+// expanded locally (e.g. BYSETPOS or sub-daily frequencies).
+var ErrUnsupportedRecurrence = errors.New("recurrence rule cannot be expanded")
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+type rrule struct {
+	freq     string
+	interval int
+	count    int
+	until    time.Time
+	byDay    []time.Weekday
+	parts    []string // every part except FREQ, so the rule can be rewritten
+	simple   bool     // only parts ExpandRecurrence understands
+	hasByDay bool
+}
+
+// ValidateRecurrence checks that every line is a well-formed RRULE, EXRULE,
+// RDATE or EXDATE property as accepted by calendar.Event.Recurrence.
+func ValidateRecurrence(lines []string) error {
+	for _, line := range lines {
+		switch recurrenceProperty(line) {
+		case "RRULE", "EXRULE":
+			if _, err := parseRRule(line); err != nil {
+				return err
+			}
+		case "RDATE", "EXDATE":
+			if _, _, err := parseDateList(line, time.UTC); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported recurrence line %q", line)
+		}
+	}
+	return nil
+}
+
+// ExpandRecurrence returns the start times of a series beginning at start,
+// limited to occurrences before until. EXDATE values are removed and RDATE
+// values added.
+func ExpandRecurrence(lines []string, start, until time.Time) ([]time.Time, error) {
+	var occurrences []time.Time
+	var excluded []time.Time
+
+	for _, line := range lines {
+		switch recurrenceProperty(line) {
+		case "RRULE":
+			rule, err := parseRRule(line)
+			if err != nil {
+				return nil, err
+			}
+			ts, err := rule.expand(start, until)
+			if err != nil {
+				return nil, err
+			}
+			occurrences = append(occurrences, ts...)
+		case "RDATE":
+			ts, _, err := parseDateList(line, start.Location())
+			if err != nil {
+				return nil, err
+			}
+			for _, t := range ts {
+				if t.Before(until) {
+					occurrences = append(occurrences, t)
+				}
+			}
+		case "EXDATE":
+			ts, _, err := parseDateList(line, start.Location())
+			if err != nil {
+				return nil, err
+			}
+			excluded = append(excluded, ts...)
+		case "EXRULE":
+			return nil, ErrUnsupportedRecurrence
+		}
+	}
+
+	// The first instance of a series is always its own start.
+	if start.Before(until) {
+		occurrences = append(occurrences, start)
+	}
+
+	sort.Slice(occurrences, func(i, j int) bool { return occurrences[i].Before(occurrences[j]) })
+
+	var result []time.Time
+	for i, t := range occurrences {
+		if i > 0 && t.Equal(occurrences[i-1]) {
+			continue
+		}
+		if containsTime(excluded, t) {
+			continue
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+// SplitRecurrence divides a series that starts at seriesStart into the rules
+// for the occurrences before splitAt (head) and the rules for a new series
+// starting at splitAt (tail). COUNT is redistributed between both halves.
+// All-day series get a date-valued UNTIL, as RFC 5545 requires.
+func SplitRecurrence(lines []string, seriesStart, splitAt time.Time, allDay bool) (head, tail []string, err error) {
+	for _, line := range lines {
+		switch recurrenceProperty(line) {
+		case "RRULE", "EXRULE":
+			rule, err := parseRRule(line)
+			if err != nil {
+				return nil, nil, err
+			}
+			name := recurrenceProperty(line)
+			if rule.count == 0 {
+				until := splitAt.Add(-time.Second).UTC().Format(recurrenceUTCLayout)
+				if allDay {
+					until = splitAt.AddDate(0, 0, -1).Format(recurrenceDateLayout)
+				}
+				head = append(head, rule.format(name, "UNTIL="+until))
+				tail = append(tail, line)
+				continue
+			}
+
+			before, err := rule.expand(seriesStart, splitAt)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(before) >= rule.count {
+				return nil, nil, fmt.Errorf("series has no occurrences after %s", splitAt.Format(time.RFC3339))
+			}
+			head = append(head, rule.format(name, "COUNT="+strconv.Itoa(len(before))))
+			tail = append(tail, rule.format(name, "COUNT="+strconv.Itoa(rule.count-len(before))))
+		case "RDATE":
+			ts, raw, err := parseDateList(line, seriesStart.Location())
+			if err != nil {
+				return nil, nil, err
+			}
+			prefix := line[:strings.Index(line, ":")+1]
+			var headValues, tailValues []string
+			for i, t := range ts {
+				if t.Before(splitAt) {
+					headValues = append(headValues, raw[i])
+				} else {
+					tailValues = append(tailValues, raw[i])
+				}
+			}
+			if len(headValues) > 0 {
+				head = append(head, prefix+strings.Join(headValues, ","))
+			}
+			if len(tailValues) > 0 {
+				tail = append(tail, prefix+strings.Join(tailValues, ","))
+			}
+		default:
+			head = append(head, line)
+			tail = append(tail, line)
+		}
+	}
+	return head, tail, nil
+}
+
+func recurrenceProperty(line string) string {
+	end := strings.IndexAny(line, ":;")
+	if end < 0 {
+		return strings.ToUpper(line)
+	}
+	return strings.ToUpper(line[:end])
+}
+
+func parseRRule(line string) (*rrule, error) {
+	idx := strings.Index(line, ":")
+	if idx < 0 {
+		return nil, fmt.Errorf("invalid recurrence rule %q", line)
+	}
+
+	rule := &rrule{interval: 1, simple: true}
+	for _, part := range strings.Split(line[idx+1:], ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid recurrence rule part %q", part)
+		}
+		key = strings.ToUpper(key)
+
+		switch key {
+		case "FREQ":
+			rule.freq = strings.ToUpper(value)
+			continue
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", value)
+			}
+			rule.interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", value)
+			}
+			rule.count = n
+		case "UNTIL":
+			t, err := parseRecurrenceTime(value, time.UTC)
+			if err != nil {
+				return nil, fmt.Errorf("invalid UNTIL %q", value)
+			}
+			rule.until = t
+		case "BYDAY":
+			rule.hasByDay = true
+			for _, code := range strings.Split(value, ",") {
+				day, ok := weekdayCodes[strings.ToUpper(code)]
+				if !ok {
+					// Ordinal forms such as 1MO or -1FR are valid but not expandable.
+					rule.simple = false
+					continue
+				}
+				rule.byDay = append(rule.byDay, day)
+			}
+		case "WKST":
+		default:
+			rule.simple = false
+		}
+		rule.parts = append(rule.parts, key+"="+value)
+	}
+
+	switch rule.freq {
+	case "SECONDLY", "MINUTELY", "HOURLY":
+		rule.simple = false
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	case "":
+		return nil, fmt.Errorf("recurrence rule %q has no FREQ", line)
+	default:
+		return nil, fmt.Errorf("invalid FREQ %q", rule.freq)
+	}
+	if rule.count > 0 && !rule.until.IsZero() {
+		return nil, fmt.Errorf("recurrence rule %q cannot set both COUNT and UNTIL", line)
+	}
+	if rule.hasByDay && rule.freq != "WEEKLY" {
+		rule.simple = false
+	}
+	return rule, nil
+}
+
+// format rewrites the rule with COUNT and UNTIL replaced by limit.
+func (r *rrule) format(name, limit string) string {
+	parts := []string{"FREQ=" + r.freq}
+	for _, part := range r.parts {
+		if strings.HasPrefix(part, "COUNT=") || strings.HasPrefix(part, "UNTIL=") {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	parts = append(parts, limit)
+	return name + ":" + strings.Join(parts, ";")
+}
+
+// expand generates rule occurrences from start (inclusive) up to limit
+// (exclusive), honouring COUNT and UNTIL.
+func (r *rrule) expand(start, limit time.Time) ([]time.Time, error) {
+	if !r.simple {
+		return nil, ErrUnsupportedRecurrence
+	}
+
+	var result []time.Time
+	generated := 0
+	emit := func(t time.Time) bool {
+		if t.Before(start) {
+			return true
+		}
+		if !r.until.IsZero() && t.After(r.until) {
+			return false
+		}
+		if r.count > 0 && generated >= r.count {
+			return false
+		}
+		if !t.Before(limit) || generated >= maxRecurrenceOccurrences {
+			return false
+		}
+		generated++
+		result = append(result, t)
+		return true
+	}
+
+	switch r.freq {
+	case "DAILY":
+		for i := 0; ; i++ {
+			if !emit(start.AddDate(0, 0, i*r.interval)) {
+				return result, nil
+			}
+		}
+	case "WEEKLY":
+		days := r.byDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		offsets := make([]int, 0, len(days))
+		for _, d := range days {
+			offsets = append(offsets, (int(d)+6)%7) // Monday-based week
+		}
+		sort.Ints(offsets)
+		weekStart := start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+		for w := 0; ; w++ {
+			base := weekStart.AddDate(0, 0, 7*w*r.interval)
+			for _, off := range offsets {
+				if !emit(base.AddDate(0, 0, off)) {
+					return result, nil
+				}
+			}
+		}
+	case "MONTHLY", "YEARLY":
+		for i := 0; ; i++ {
+			months := i * r.interval
+			if r.freq == "YEARLY" {
+				months *= 12
+			}
+			t := time.Date(start.Year(), start.Month()+time.Month(months), start.Day(),
+				start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+			if t.Day() != start.Day() {
+				// Skip months without this day (e.g. the 31st), as RFC 5545 does.
+				if !t.Before(limit) {
+					return result, nil
+				}
+				continue
+			}
+			if !emit(t) {
+				return result, nil
+			}
+		}
+	}
+	return result, nil
+}
+
+// parseDateList parses the values of an RDATE or EXDATE line and also returns
+// the raw value strings so the line can be rebuilt.
+func parseDateList(line string, loc *time.Location) ([]time.Time, []string, error) {
+	idx := strings.Index(line, ":")
+	if idx < 0 {
+		return nil, nil, fmt.Errorf("invalid recurrence line %q", line)
+	}
+	for _, param := range strings.Split(line[:idx], ";")[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		if strings.EqualFold(key, "TZID") {
+			tz, err := time.LoadLocation(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid TZID %q", value)
+			}
+			loc = tz
+		}
+	}
+
+	var times []time.Time
+	raw := strings.Split(line[idx+1:], ",")
+	for _, value := range raw {
+		t, err := parseRecurrenceTime(value, loc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid date %q in %q", value, line)
+		}
+		times = append(times, t)
+	}
+	return times, raw, nil
+}
+
+func parseRecurrenceTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(recurrenceUTCLayout, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(recurrenceLocalLayout, value, loc); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(recurrenceDateLayout, value, loc)
+}
+
+func containsTime(times []time.Time, t time.Time) bool {
+	for _, x := range times {
+		if x.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// InstanceID builds the ID Google assigns to one instance of a recurring
+// series, e.g. "abc123_20240105T090000Z" or "abc123_20240105" for all-day.
+func InstanceID(masterID string, start time.Time, allDay bool) string {
+	if allDay {
+		return masterID + "_" + start.Format(recurrenceDateLayout)
+	}
+	return masterID + "_" + start.UTC().Format(recurrenceUTCLayout)
+}
+
+// ParseInstanceID splits an instance ID built by InstanceID into the series
+// master ID and the original start of the instance.
+func ParseInstanceID(id string) (masterID string, start time.Time, ok bool) {
+	idx := strings.LastIndex(id, "_")
+	if idx <= 0 {
+		return "", time.Time{}, false
+	}
+	suffix := id[idx+1:]
+	if t, err := time.Parse(recurrenceUTCLayout, suffix); err == nil {
+		return id[:idx], t, true
+	}
+	if t, err := time.Parse(recurrenceDateLayout, suffix); err == nil {
+		return id[:idx], t, true
+	}
+	return "", time.Time{}, false
+}
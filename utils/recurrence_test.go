@@ -0,0 +1,249 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load location %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestExpandRecurrence(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	utc := func(y int, m time.Month, d, h int) time.Time { return time.Date(y, m, d, h, 0, 0, 0, time.UTC) }
+	start := utc(2030, 1, 7, 9) // a Monday
+
+	tests := []struct {
+		name  string
+		lines []string
+		start time.Time
+		until time.Time
+		want  []time.Time
+	}{
+		{
+			name:  "daily with interval and count",
+			lines: []string{"RRULE:FREQ=DAILY;INTERVAL=2;COUNT=3"},
+			start: start,
+			until: start.AddDate(1, 0, 0),
+			want:  []time.Time{start, utc(2030, 1, 9, 9), utc(2030, 1, 11, 9)},
+		},
+		{
+			name:  "daily stops at the window",
+			lines: []string{"RRULE:FREQ=DAILY"},
+			start: start,
+			until: utc(2030, 1, 10, 0),
+			want:  []time.Time{start, utc(2030, 1, 8, 9), utc(2030, 1, 9, 9)},
+		},
+		{
+			name:  "weekly by day",
+			lines: []string{"RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4"},
+			start: start,
+			until: start.AddDate(1, 0, 0),
+			want:  []time.Time{start, utc(2030, 1, 9, 9), utc(2030, 1, 14, 9), utc(2030, 1, 16, 9)},
+		},
+		{
+			name:  "weekly until is inclusive",
+			lines: []string{"RRULE:FREQ=WEEKLY;UNTIL=20300121T090000Z"},
+			start: start,
+			until: start.AddDate(1, 0, 0),
+			want:  []time.Time{start, utc(2030, 1, 14, 9), utc(2030, 1, 21, 9)},
+		},
+		{
+			name:  "monthly skips months without the day",
+			lines: []string{"RRULE:FREQ=MONTHLY;COUNT=3"},
+			start: utc(2030, 1, 31, 9),
+			until: utc(2031, 1, 1, 0),
+			want:  []time.Time{utc(2030, 1, 31, 9), utc(2030, 3, 31, 9), utc(2030, 5, 31, 9)},
+		},
+		{
+			name:  "exdate removes an occurrence",
+			lines: []string{"RRULE:FREQ=DAILY;COUNT=3", "EXDATE:20300108T090000Z"},
+			start: start,
+			until: start.AddDate(1, 0, 0),
+			want:  []time.Time{start, utc(2030, 1, 9, 9)},
+		},
+		{
+			name:  "rdate adds an occurrence",
+			lines: []string{"RRULE:FREQ=DAILY;COUNT=2", "RDATE:20300120T090000Z"},
+			start: start,
+			until: start.AddDate(1, 0, 0),
+			want:  []time.Time{start, utc(2030, 1, 8, 9), utc(2030, 1, 20, 9)},
+		},
+		{
+			name:  "weekly keeps local time across DST",
+			lines: []string{"RRULE:FREQ=WEEKLY;COUNT=4"},
+			start: time.Date(2030, 10, 14, 9, 0, 0, 0, berlin),
+			until: utc(2031, 1, 1, 0),
+			want: []time.Time{
+				utc(2030, 10, 14, 7), utc(2030, 10, 21, 7),
+				utc(2030, 10, 28, 8), utc(2030, 11, 4, 8),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandRecurrence(tt.lines, tt.start, tt.until)
+			if err != nil {
+				t.Fatalf("ExpandRecurrence: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences %v, want %v", len(got), got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("occurrence %d = %s, want %s", i, got[i].UTC(), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExpandRecurrenceUnsupported(t *testing.T) {
+	start := time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+	for _, line := range []string{"RRULE:FREQ=MONTHLY;BYDAY=1MO", "RRULE:FREQ=HOURLY", "RRULE:FREQ=MONTHLY;BYSETPOS=1;BYDAY=MO"} {
+		if _, err := ExpandRecurrence([]string{line}, start, start.AddDate(1, 0, 0)); err != ErrUnsupportedRecurrence {
+			t.Errorf("%s: got %v, want ErrUnsupportedRecurrence", line, err)
+		}
+	}
+}
+
+func TestValidateRecurrence(t *testing.T) {
+	tests := []struct {
+		line    string
+		wantErr bool
+	}{
+		{"RRULE:FREQ=WEEKLY;BYDAY=MO,FR;COUNT=5", false},
+		{"RRULE:FREQ=DAILY;UNTIL=20300101", false},
+		{"EXDATE;TZID=Europe/Berlin:20300107T090000", false},
+		{"RRULE:COUNT=5", true},
+		{"RRULE:FREQ=FORTNIGHTLY", true},
+		{"RRULE:FREQ=DAILY;COUNT=0", true},
+		{"RRULE:FREQ=DAILY;COUNT=2;UNTIL=20300101", true},
+		{"EXDATE;TZID=Mars/Olympus:20300107T090000", true},
+		{"DTSTART:20300107T090000Z", true},
+	}
+	for _, tt := range tests {
+		err := ValidateRecurrence([]string{tt.line})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateRecurrence(%q) error = %v, wantErr %v", tt.line, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSplitRecurrence(t *testing.T) {
+	start := time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		lines    []string
+		splitAt  time.Time
+		allDay   bool
+		wantHead []string
+		wantTail []string
+	}{
+		{
+			name:     "count is redistributed",
+			lines:    []string{"RRULE:FREQ=WEEKLY;COUNT=10"},
+			splitAt:  start.AddDate(0, 0, 21),
+			wantHead: []string{"RRULE:FREQ=WEEKLY;COUNT=3"},
+			wantTail: []string{"RRULE:FREQ=WEEKLY;COUNT=7"},
+		},
+		{
+			name:     "open series ends before the split",
+			lines:    []string{"RRULE:FREQ=DAILY;INTERVAL=2", "EXDATE:20300109T090000Z"},
+			splitAt:  start.AddDate(0, 0, 4),
+			wantHead: []string{"RRULE:FREQ=DAILY;INTERVAL=2;UNTIL=20300111T085959Z", "EXDATE:20300109T090000Z"},
+			wantTail: []string{"RRULE:FREQ=DAILY;INTERVAL=2", "EXDATE:20300109T090000Z"},
+		},
+		{
+			name:     "all-day series get a date until",
+			lines:    []string{"RRULE:FREQ=DAILY"},
+			splitAt:  time.Date(2030, 1, 10, 0, 0, 0, 0, time.UTC),
+			allDay:   true,
+			wantHead: []string{"RRULE:FREQ=DAILY;UNTIL=20300109"},
+			wantTail: []string{"RRULE:FREQ=DAILY"},
+		},
+		{
+			name:     "rdates follow their side of the split",
+			lines:    []string{"RRULE:FREQ=DAILY;COUNT=2", "RDATE:20300105T090000Z,20300201T090000Z"},
+			splitAt:  start.AddDate(0, 0, 1),
+			wantHead: []string{"RRULE:FREQ=DAILY;COUNT=1", "RDATE:20300105T090000Z"},
+			wantTail: []string{"RRULE:FREQ=DAILY;COUNT=1", "RDATE:20300201T090000Z"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			head, tail, err := SplitRecurrence(tt.lines, start, tt.splitAt, tt.allDay)
+			if err != nil {
+				t.Fatalf("SplitRecurrence: %v", err)
+			}
+			if !reflect.DeepEqual(head, tt.wantHead) {
+				t.Errorf("head = %q, want %q", head, tt.wantHead)
+			}
+			if !reflect.DeepEqual(tail, tt.wantTail) {
+				t.Errorf("tail = %q, want %q", tail, tt.wantTail)
+			}
+		})
+	}
+
+	if _, _, err := SplitRecurrence([]string{"RRULE:FREQ=DAILY;COUNT=2"}, start, start.AddDate(0, 0, 5), false); err == nil {
+		t.Error("splitting after the last occurrence should fail")
+	}
+}
+
+// A series written as 09:00+02:00 in Europe/Berlin must be split by its local
+// occurrences: after DST ends the 5th one is at 08:00Z, not 07:00Z.
+func TestSplitRecurrenceAcrossDST(t *testing.T) {
+	dt := &calendar.EventDateTime{DateTime: "2030-10-07T09:00:00+02:00", TimeZone: "Europe/Berlin"}
+	seriesStart := ParseSeriesStart(dt)
+	splitAt := time.Date(2030, 11, 4, 8, 0, 0, 0, time.UTC)
+
+	head, tail, err := SplitRecurrence([]string{"RRULE:FREQ=WEEKLY;COUNT=10"}, seriesStart, splitAt, false)
+	if err != nil {
+		t.Fatalf("SplitRecurrence: %v", err)
+	}
+	if want := []string{"RRULE:FREQ=WEEKLY;COUNT=4"}; !reflect.DeepEqual(head, want) {
+		t.Errorf("head = %q, want %q", head, want)
+	}
+	if want := []string{"RRULE:FREQ=WEEKLY;COUNT=6"}; !reflect.DeepEqual(tail, want) {
+		t.Errorf("tail = %q, want %q", tail, want)
+	}
+}
+
+func TestInstanceID(t *testing.T) {
+	tests := []struct {
+		start  time.Time
+		allDay bool
+		want   string
+	}{
+		{time.Date(2030, 1, 7, 10, 0, 0, 0, time.FixedZone("", 3600)), false, "abc_20300107T090000Z"},
+		{time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC), true, "abc_20300107"},
+	}
+	for _, tt := range tests {
+		id := InstanceID("abc", tt.start, tt.allDay)
+		if id != tt.want {
+			t.Errorf("InstanceID = %q, want %q", id, tt.want)
+		}
+		master, start, ok := ParseInstanceID(id)
+		if !ok || master != "abc" || !start.Equal(tt.start) {
+			t.Errorf("ParseInstanceID(%q) = %q, %s, %v", id, master, start, ok)
+		}
+	}
+
+	for _, id := range []string{"abc", "_20300107", "abc_tomorrow"} {
+		if _, _, ok := ParseInstanceID(id); ok {
+			t.Errorf("ParseInstanceID(%q) should fail", id)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"backend/models"
+	"sort"
+	"time"
+)
+
+// MergeTimeRanges sorts ranges and joins the ones that overlap or touch.
+// Empty ranges are dropped.
+func MergeTimeRanges(ranges []models.TimeRange) []models.TimeRange {
+	sorted := make([]models.TimeRange, 0, len(ranges))
+	for _, r := range ranges {
+		if r.End.After(r.Start) {
+			sorted = append(sorted, r)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	merged := []models.TimeRange{}
+	for _, r := range sorted {
+		last := len(merged) - 1
+		if last >= 0 && !r.Start.After(merged[last].End) {
+			if r.End.After(merged[last].End) {
+				merged[last].End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// FreeTimeRanges returns the gaps between busy ranges within [from, to).
+// busy must be merged, as returned by MergeTimeRanges.
+func FreeTimeRanges(busy []models.TimeRange, from, to time.Time) []models.TimeRange {
+	free := []models.TimeRange{}
+	cursor := from
+	for _, b := range busy {
+		if !b.End.After(cursor) {
+			continue
+		}
+		if !b.Start.Before(to) {
+			break
+		}
+		if b.Start.After(cursor) {
+			free = append(free, models.TimeRange{Start: cursor, End: b.Start})
+		}
+		cursor = b.End
+	}
+	if cursor.Before(to) {
+		free = append(free, models.TimeRange{Start: cursor, End: to})
+	}
+	return free
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"backend/models"
+	"fmt"
+	"time"
+
+	// Embed the IANA database so validation does not depend on the host.
+	_ "time/tzdata"
+)
+
+// ValidateTimeZone checks that name is an IANA time zone such as
+// "Europe/Berlin" or "America/New_York".
+func ValidateTimeZone(name string) error {
+	if name == "" || name == "Local" {
+		return fmt.Errorf("invalid time zone %q", name)
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("invalid time zone %q", name)
+	}
+	return nil
+}
+
+// EventsInTimeZone renders the times of timed events in loc. All-day events
+// are left alone since their dates must not move.
+func EventsInTimeZone(events []models.Event, loc *time.Location) {
+	for i := range events {
+		if events[i].AllDay {
+			continue
+		}
+		events[i].StartTime = events[i].StartTime.In(loc)
+		events[i].EndTime = events[i].EndTime.In(loc)
+		if events[i].OriginalStartTime != nil {
+			original := events[i].OriginalStartTime.In(loc)
+			events[i].OriginalStartTime = &original
+		}
+	}
+}